@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/notnil/chess"
+)
+
+// 아레나 결과 (new 쪽 관점)
+type arenaStats struct {
+	Games  int     `json:"games"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Score  float64 `json:"score"` // (승 + 무/2) / 판 수
+	Elo    float64 `json:"elo"`   // old 대비 대략적인 Elo 차이
+}
+
+// 한 상태의 수별 Q값입니다. qValues와 같지만 주어진 Q테이블 파일에서 읽습니다.
+func (b brainFile) qRow(state string) map[string]float64 {
+	row := make(map[string]float64, len(b.QTable[state]))
+	for move, q := range b.QTable[state] {
+		row[move] = q
+	}
+	if ai.DoubleQ {
+		for move, q := range b.QB[state] {
+			row[move] += q
+		}
+	}
+	return row
+}
+
+// -arena old.json new.json -games N
+// 두 Q테이블을 색을 번갈아 가며 N판 대국시킵니다. 수는 bestMove처럼 점수가 가장 높은 수를 고르고
+// (오프닝 북·epsilon 없음) 학습은 하지 않습니다.
+func runArena(oldPath, newPath string, games int, r *rand.Rand) (arenaStats, error) {
+	var stats arenaStats
+	if games <= 0 {
+		return stats, errors.New("games must be a positive number")
+	}
+	oldBrain, err := readBrain(oldPath)
+	if err != nil {
+		return stats, err
+	}
+	newBrain, err := readBrain(newPath)
+	if err != nil {
+		return stats, err
+	}
+
+	tt := make(transTable)
+	for i := 0; i < games; i++ {
+		// 짝수 판은 new가 백, 홀수 판은 new가 흑입니다.
+		newColor := chess.White
+		white, black := newBrain, oldBrain
+		if i%2 == 1 {
+			newColor = chess.Black
+			white, black = oldBrain, newBrain
+		}
+		switch outcome := playArenaGame(white, black, tt, r); {
+		case outcome == chess.Draw:
+			stats.Draws++
+		case (outcome == chess.WhiteWon) == (newColor == chess.White):
+			stats.Wins++
+		default:
+			stats.Losses++
+		}
+		stats.Games++
+	}
+	stats.Score = (float64(stats.Wins) + float64(stats.Draws)/2) / float64(stats.Games)
+	stats.Elo = eloDiff(stats.Score, stats.Games)
+	return stats, nil
+}
+
+// 한 판을 둡니다. 세 번 반복되거나 selfPlayMaxPlies를 넘으면 무승부입니다.
+func playArenaGame(white, black brainFile, tt transTable, r *rand.Rand) chess.Outcome {
+	game := chess.NewGame()
+	for game.Outcome() == chess.NoOutcome && len(game.Moves()) < selfPlayMaxPlies {
+		pos := game.Position()
+		brain := white
+		if pos.Turn() == chess.Black {
+			brain = black
+		}
+		ranked := rankMoves(pos, pos.ValidMoves(), brain.qRow(stateKey(pos)), tt, nil)
+		if len(ranked) == 0 {
+			break
+		}
+		if !playMove(game, topMove(ranked, r)) {
+			break
+		}
+		for _, m := range game.EligibleDraws() {
+			if m == chess.ThreefoldRepetition {
+				game.Draw(m)
+			}
+		}
+	}
+	if game.Outcome() == chess.NoOutcome {
+		return chess.Draw
+	}
+	return game.Outcome()
+}
+
+// 득점률로 대략적인 Elo 차이를 계산합니다. 400·log10(score / (1 - score))
+// 전승·전패면 무한대가 되므로 반 판만 덜 이긴(진) 것으로 잘라 계산합니다.
+func eloDiff(score float64, games int) float64 {
+	limit := 0.5 / float64(games)
+	score = math.Min(math.Max(score, limit), 1-limit)
+	return 400 * math.Log10(score/(1-score))
+}
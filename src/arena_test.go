@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBrain(t *testing.T, brain brainFile) string {
+	t.Helper()
+	data, err := json.Marshal(brain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "brain.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArenaTinyMatch(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	oldPath := writeBrain(t, brainFile{QTable: map[string]map[string]float64{}})
+	newPath := writeBrain(t, brainFile{QTable: map[string]map[string]float64{start: {"e2e4": 1}}})
+
+	stats, err := runArena(oldPath, newPath, 2, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games != 2 || stats.Wins+stats.Draws+stats.Losses != 2 {
+		t.Fatalf("stats = %+v, want 2 games", stats)
+	}
+	if want := (float64(stats.Wins) + float64(stats.Draws)/2) / 2; stats.Score != want {
+		t.Errorf("score = %v, want %v", stats.Score, want)
+	}
+	if stats.Elo != eloDiff(stats.Score, 2) {
+		t.Errorf("elo = %v, want %v", stats.Elo, eloDiff(stats.Score, 2))
+	}
+	// 학습하지 않으므로 Q테이블은 그대로입니다.
+	if len(ai.QTable) != 0 {
+		t.Errorf("arena changed the live Q-table: %v", ai.QTable)
+	}
+
+	if _, err := runArena(oldPath, newPath, 0, ai.rng); err == nil {
+		t.Error("games=0 was accepted")
+	}
+	if _, err := runArena(filepath.Join(t.TempDir(), "missing.json"), newPath, 1, ai.rng); err == nil {
+		t.Error("missing file was accepted")
+	}
+}
+
+func TestEloDiff(t *testing.T) {
+	if got := eloDiff(0.5, 10); got != 0 {
+		t.Errorf("eloDiff(0.5) = %v, want 0", got)
+	}
+	if got := eloDiff(0.75, 10); math.Abs(got-190.85) > 0.01 {
+		t.Errorf("eloDiff(0.75) = %v, want about 190.85", got)
+	}
+	// 전승은 반 판 덜 이긴 것으로 잘라 유한한 값이 됩니다.
+	if got, want := eloDiff(1, 4), eloDiff(0.875, 4); got != want || math.IsInf(got, 0) {
+		t.Errorf("eloDiff(1) = %v, want %v", got, want)
+	}
+	if got := eloDiff(0, 4); math.Abs(got+eloDiff(1, 4)) > 1e-9 {
+		t.Errorf("eloDiff(0) = %v, want %v", got, -eloDiff(1, 4))
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// 설정의 admin_token보다 우선하는 환경 변수. 토큰을 설정 파일에 적어 두지 않을 때 씁니다.
+const adminTokenEnv = "CHESS_ADMIN_TOKEN"
+
+// 관리용 엔드포인트에 요구할 토큰. 비어 있으면 인증하지 않습니다.
+func adminToken() string {
+	return envOr(adminTokenEnv, ai.AdminToken)
+}
+
+// next 앞에서 "Authorization: Bearer <token>" 헤더를 확인합니다. 없거나 틀리면 401입니다.
+// token이 비어 있으면 next를 그대로 돌려줍니다.
+func requireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		// 비교 시간으로 토큰을 알아낼 수 없게 일정 시간 비교를 씁니다.
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chess-ai"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	h := requireAdmin("secret", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) })
+	for _, c := range []struct {
+		name, header string
+		want         int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"not bearer", "Basic secret", http.StatusUnauthorized},
+		{"correct token", "Bearer secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/save", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, rec.Code, c.want)
+		}
+		if c.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate header", c.name)
+		}
+	}
+
+	// 토큰을 설정하지 않으면 인증하지 않습니다.
+	rec := httptest.NewRecorder()
+	requireAdmin("", func(w http.ResponseWriter, r *http.Request) {})(rec, httptest.NewRequest(http.MethodPost, "/save", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("no admin token configured: status %d, want 200", rec.Code)
+	}
+}
+
+func TestAdminTokenEnvOverridesConfig(t *testing.T) {
+	newTestAI(t)
+	ai.AdminToken = "from-config"
+	t.Setenv(adminTokenEnv, "")
+	if got := adminToken(); got != "from-config" {
+		t.Errorf("adminToken() = %q, want the config value", got)
+	}
+	t.Setenv(adminTokenEnv, "from-env")
+	if got := adminToken(); got != "from-env" {
+		t.Errorf("adminToken() = %q, want the environment value", got)
+	}
+}
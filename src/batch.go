@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+)
+
+// 한 번에 평가할 수 있는 FEN 최대 개수
+const maxBatchFENs = 1000
+
+// 일괄 평가 결과 한 건. 읽을 수 없는 FEN이면 error를 채우고 score는 0입니다.
+type batchResult struct {
+	FEN      string  `json:"fen"`
+	Score    float64 `json:"score"`               // 둘 차례 관점의 정적 평가값 (evaluate)
+	BestMove string  `json:"best_move,omitempty"` // /move와 같은 점수로 가장 좋은 수. 합법 수가 없으면 비어 있음
+	Error    string  `json:"error,omitempty"`
+}
+
+// fens를 workers개의 고루틴에 나눠 평가합니다. 결과는 입력 순서를 따릅니다.
+// 작업자마다 치환표를 따로 가집니다. 설정을 읽으므로 configMu 읽기 잠금 아래에서 호출합니다.
+// Q값은 포지션마다 ai.mu 읽기 잠금을 잠깐 잡고 복사하므로, 탐색하는 동안 /move나 학습이 기다리지 않습니다.
+// 수를 고를 때 epsilon이나 동점 무작위 선택 없이 점수가 가장 높은 수를 돌려주고, 학습하지 않습니다.
+func evaluateBatch(fens []string, workers int) []batchResult {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]batchResult, len(fens))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(tt transTable) {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = evaluateOne(fens[i], tt)
+			}
+		}(make(transTable))
+	}
+	for i := range fens {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func evaluateOne(fen string, tt transTable) batchResult {
+	res := batchResult{FEN: fen}
+	game, err := parseFEN(fen)
+	if err != nil {
+		res.Error = "invalid FEN: " + err.Error()
+		return res
+	}
+	pos := game.Position()
+	res.Score = evaluate(pos)
+	if moves := pos.ValidMoves(); len(moves) > 0 {
+		ai.mu.RLock()
+		qrow := qValues(stateKey(pos))
+		ai.mu.RUnlock()
+		ranked := rankMoves(pos, moves, qrow, tt, nil)
+		res.BestMove = ranked[0].move.String()
+	}
+	return res
+}
+
+// POST /evaluate/batch {"fens": ["...", ...]}
+// 여러 포지션을 한 번에 평가해 {"results": [{fen, score, best_move}, ...]}로 돌려줍니다.
+// 잘못된 FEN은 전체를 실패시키지 않고 그 항목의 error에만 적습니다.
+func evaluateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		FENs    []string `json:"fens"`
+		Workers int      `json:"workers"` // 0이면 CPU 개수
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if len(req.FENs) == 0 || len(req.FENs) > maxBatchFENs {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("fens must have between 1 and %d entries", maxBatchFENs))
+		return
+	}
+	workers := min(req.Workers, runtime.NumCPU())
+
+	configMu.RLock()
+	results := evaluateBatch(req.FENs, workers)
+	configMu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
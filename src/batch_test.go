@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestEvaluateBatchMixedFENs(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"not a fen",
+		"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", // Ra8#
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN w KQkq - 0 1",       // 랭크 칸 수가 모자람
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", // 체크메이트: 둘 수 없음
+	}
+	rec := postJSON(t, evaluateBatchHandler, "/evaluate/batch", map[string]interface{}{"fens": fens, "workers": 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != len(fens) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(fens))
+	}
+	for i, res := range resp.Results {
+		if res.FEN != fens[i] {
+			t.Errorf("result %d is for %q, want input order", i, res.FEN)
+		}
+		invalid := i == 1 || i == 3
+		if invalid != (res.Error != "") {
+			t.Errorf("result %d: error %q", i, res.Error)
+		}
+	}
+	if resp.Results[0].BestMove == "" {
+		t.Error("start position has no best move")
+	}
+	if got := resp.Results[2].BestMove; got != "a1a8" {
+		t.Errorf("mate in one: best move %q, want a1a8", got)
+	}
+	if got := resp.Results[4]; got.Error != "" || got.BestMove != "" {
+		t.Errorf("checkmated position: %+v, want no move and no error", got)
+	}
+
+	if rec := postJSON(t, evaluateBatchHandler, "/evaluate/batch", map[string]interface{}{"fens": []string{}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: status %d, want 400", rec.Code)
+	}
+}
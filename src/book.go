@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// 오프닝 북 파일. 한 줄에 시작 포지션부터의 SAN 수순 하나를 적습니다. (#으로 시작하면 주석)
+const bookFile = "book.txt"
+
+// 정규화된 FEN → 북에 있는 후보 수(UCI) 목록
+var openingBook = make(map[string][]string)
+
+// 북 파일을 읽어 포지션별 후보 수 표를 만듭니다. 잘못된 수가 나오면 그 줄의 나머지는 무시합니다.
+func loadBook(path string) map[string][]string {
+	book := make(map[string][]string)
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("오프닝 북을 읽지 못했습니다", "path", path, "err", err)
+		}
+		return book
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		game := chess.NewGame()
+		for _, san := range strings.Fields(line) {
+			pos := game.Position()
+			m, err := chess.AlgebraicNotation{}.Decode(pos, san)
+			if err != nil || game.Move(m) != nil {
+				break
+			}
+			key := normalizeFEN(pos.String())
+			if !containsString(book[key], m.String()) {
+				book[key] = append(book[key], m.String())
+			}
+		}
+	}
+	return book
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 북에 있는 포지션이면 후보 수 중 하나를 무작위로 골라 돌려줍니다. 없으면 nil입니다.
+func bookMove(pos *chess.Position, r *rand.Rand) *chess.Move {
+	candidates := openingBook[normalizeFEN(pos.String())]
+	if len(candidates) == 0 {
+		return nil
+	}
+	m, err := chess.UCINotation{}.Decode(pos, candidates[r.Intn(len(candidates))])
+	if err != nil {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+const configFile = "config.json"
+
+// 탐색은 설정(평가 가중치, 탐색 깊이 등)을 오래 읽으므로 ai.mu 대신 이 읽기 잠금을 잡고 합니다.
+// 설정을 바꿀 때는 이 쓰기 잠금과 ai.mu 쓰기 잠금을 모두 잡으므로, 둘 중 하나만 잡아도 설정을 읽을 수 있습니다.
+// 두 잠금을 함께 잡을 때는 항상 configMu를 먼저 잡습니다.
+var configMu sync.RWMutex
+
+// 기물 가치 (폰=10 단위)
+type PieceValues struct {
+	Pawn   float64 `json:"pawn"`
+	Knight float64 `json:"knight"`
+	Bishop float64 `json:"bishop"`
+	Rook   float64 `json:"rook"`
+	Queen  float64 `json:"queen"`
+	King   float64 `json:"king"`
+}
+
+// Config는 config.json에서 읽는 학습·평가 설정입니다.
+// 파일이 없거나 일부 항목이 빠지면 기본값을 씁니다.
+type Config struct {
+	PieceValues       PieceValues `json:"piece_values"`
+	WinReward         float64     `json:"win_reward"`
+	LossReward        float64     `json:"loss_reward"`
+	DrawReward        float64     `json:"draw_reward"`
+	StaleReward       float64     `json:"stalemate_reward"`   // 우세한 상황에서 스테일메이트를 낸 경우
+	Alpha             float64     `json:"alpha"`              // 학습률 (adaptive_alpha면 하한)
+	ShapingWeight     float64     `json:"shaping_weight"`     // 수마다 평가값 변화에 곱해 주는 중간 보상 가중치 (0이면 끔)
+	AdaptiveAlpha     bool        `json:"adaptive_alpha"`     // 방문 횟수에 따라 학습률을 1/(1+n)으로 줄일지
+	Gamma             float64     `json:"gamma"`              // 할인율
+	Decay             float64     `json:"decay"`              // 종료 보상 감쇠율
+	Epsilon           float64     `json:"epsilon"`            // 무작위 탐색 확률 (decay_schedule이면 시작값)
+	EpsilonMin        float64     `json:"epsilon_min"`        // 줄어든 epsilon의 하한
+	EpsilonDecay      float64     `json:"epsilon_decay"`      // 판마다 epsilon을 줄이는 양 (linear: 빼는 값, exponential: 곱하는 값)
+	AlphaMin          float64     `json:"alpha_min"`          // 줄어든 alpha의 하한
+	AlphaDecay        float64     `json:"alpha_decay"`        // 판마다 alpha를 줄이는 양 (epsilon_decay와 같은 방식)
+	DecaySchedule     string      `json:"decay_schedule"`     // 판 수에 따라 epsilon·alpha를 줄이는 방식: "none", "linear", "exponential"
+	SelectionMode     string      `json:"selection_mode"`     // 수 선택 방식: "greedy", "epsilon", "softmax"
+	Temperature       float64     `json:"temperature"`        // softmax 온도 (높을수록 고르게 탐색)
+	QScale            float64     `json:"q_scale"`            // 수 선택에서 Q값을 ±q_scale 안의 평가 척도로 바꿈 (0이면 원래 값)
+	QWeight           float64     `json:"q_weight"`           // 수 선택 점수에서 Q값에 곱하는 비중
+	EvalWeight        float64     `json:"eval_weight"`        // 수 선택 점수에서 탐색 평가에 곱하는 비중
+	SearchDepth       int         `json:"search_depth"`       // 알파-베타 탐색 깊이
+	NullMove          bool        `json:"null_move"`          // 널 무브 가지치기로 같은 시간에 더 깊이 탐색 (엔드게임에서는 자동으로 끔)
+	MoveOrdering      bool        `json:"move_ordering"`      // 탐색에서 잡기(MVV-LVA)와 킬러 무브를 먼저 봄 (끄면 수 생성 순서, 비교용)
+	MoveTimeMs        int         `json:"move_time_ms"`       // /move 탐색 시간 제한(ms). 0보다 크면 search_depth 대신 반복 심화
+	MobilityWeight    float64     `json:"mobility_weight"`    // 둘 수 있는 수(핀·체크 무시) 1개당 가산점
+	HangingWeight     float64     `json:"hanging_weight"`     // 상대 차례에 잡힐 수 있는 기물 가치에 곱하는 감점 비율
+	BishopPairBonus   float64     `json:"bishop_pair_bonus"`  // 비숍 두 개를 가진 쪽의 가점 (판이 열릴수록 최대 1.5배)
+	OutpostBonus      float64     `json:"outpost_bonus"`      // 폰이 지키고 상대 폰이 쫓아낼 수 없는 칸의 나이트 1개당 가점
+	CheckBonus        float64     `json:"check_bonus"`        // 방금 체크를 건 쪽의 가점 (평가값 차이로 주는 중간 보상에도 반영)
+	ThreatBonus       float64     `json:"threat_bonus"`       // 방금 둔 쪽이 노리는, 지킴이 없거나 더 비싼 상대 기물 1개당 가점
+	Tempo             float64     `json:"tempo"`              // 둘 차례인 쪽의 가점
+	NStep             int         `json:"n_step"`             // n단계 보상: 다음 n수의 할인 보상 합 + n수 뒤 상태의 가치로 갱신 (1이면 한 단계)
+	Lambda            float64     `json:"lambda"`             // Q(λ) 적격 흔적 감쇠율 (0이면 끄고 n_step 갱신, 0보다 크면 n_step 무시)
+	LearningAlgo      string      `json:"learning_algo"`      // Q값 갱신 방식: "qlearning"(다음 상태의 최댓값), "sarsa"(실제로 둔 다음 수의 값)
+	LearningEnabled   bool        `json:"learning_enabled"`   // /move 대국을 기록해 학습할지 (요청의 learn이 우선, false면 Q테이블을 건드리지 않고 두기만 함)
+	DoubleQ           bool        `json:"double_q"`           // Double Q-learning: 두 Q테이블을 번갈아 갱신해 과대평가를 줄임
+	Augment           bool        `json:"augment"`            // 캐슬링 권리가 없는 포지션은 좌우 반전한 상태-수도 함께 학습
+	ReplaySize        int         `json:"replay_size"`        // 경험 재생 버퍼에 담아 둘 전이 수
+	ReplayBatch       int         `json:"replay_batch"`       // 대국마다 버퍼에서 다시 학습할 전이 수 (0이면 끔)
+	MaxStates         int         `json:"max_states"`         // Q테이블 상태 수 상한 (0이면 제한 없음)
+	Gzip              bool        `json:"gzip"`               // Q테이블을 qtable.json.gz로 압축 저장 (false면 평문 JSON). .gz가 없으면 평문 파일을 읽어 옮깁니다
+	Journal           bool        `json:"journal"`            // 저장할 때 바뀐 상태만 qtable.json.log에 덧붙이고 가끔 전체를 다시 씀 (false면 매번 전체)
+	JournalMaxMB      int         `json:"journal_max_mb"`     // 저장 로그가 이 크기(MB)를 넘으면 전체 스냅숏으로 합침
+	AutosaveSecs      int         `json:"autosave_seconds"`   // 자동 저장 주기 (0이면 끔)
+	UseBook           bool        `json:"use_book"`           // 오프닝 북(book.txt)을 먼저 찾아볼지
+	CORSOrigin        string      `json:"cors_origin"`        // Access-Control-Allow-Origin 값 (빈 문자열이면 CORS 끔)
+	RateLimit         float64     `json:"rate_limit"`         // IP당 탐색 엔드포인트(/move, /solve) 초당 요청 수 (0이면 제한 없음)
+	RateBurst         int         `json:"rate_burst"`         // 탐색 엔드포인트 순간 허용 요청 수
+	AdminRateLimit    float64     `json:"admin_rate_limit"`   // IP당 관리용 엔드포인트(/save, /reset 등) 초당 요청 수 (0이면 제한 없음)
+	AdminRateBurst    int         `json:"admin_rate_burst"`   // 관리용 엔드포인트 순간 허용 요청 수
+	TrustProxy        bool        `json:"trust_proxy"`        // 요청 제한에서 X-Forwarded-For로 클라이언트 IP를 판단 (프록시 뒤에서만 켬)
+	AdminToken        string      `json:"admin_token"`        // 관리용 엔드포인트의 Bearer 토큰 (비우면 인증 없음, CHESS_ADMIN_TOKEN이 우선)
+	RepetitionPenalty float64     `json:"repetition_penalty"` // 지지 않는 상황에서 이미 나온 포지션으로 돌아가는 수의 감점
+	Contempt          float64     `json:"contempt"`           // 탐색에서 무승부 포지션의 점수 조정폭 (학습 보상에는 영향 없음)
+	EloK              float64     `json:"elo_k"`              // Elo 레이팅 갱신 계수 K
+	ProgressEvery     int         `json:"progress_every"`     // 이 판 수마다 학습 현황을 /progress에 기록 (0이면 끔)
+}
+
+func defaultConfig() Config {
+	return Config{
+		PieceValues: PieceValues{
+			Pawn:   10.0,
+			Knight: 30.0,
+			Bishop: 30.0,
+			Rook:   50.0,
+			Queen:  90.0,
+			King:   900.0, // 왕은 절대적 가치
+		},
+		WinReward:         500.0,
+		LossReward:        -500.0, // 패배 시 기본 감점 강화
+		DrawReward:        0,
+		StaleReward:       -50,
+		Alpha:             0.1,
+		AdaptiveAlpha:     true,
+		ShapingWeight:     0.1,
+		Gamma:             0.9,
+		Decay:             0.9,
+		Epsilon:           0.15,
+		EpsilonMin:        0.01,
+		EpsilonDecay:      0.999,
+		AlphaMin:          0.01,
+		AlphaDecay:        0.999,
+		DecaySchedule:     scheduleNone,
+		SelectionMode:     selectEpsilon,
+		Temperature:       10,
+		QScale:            100,
+		QWeight:           1,
+		EvalWeight:        1,
+		SearchDepth:       3,
+		NullMove:          true,
+		MoveOrdering:      true,
+		MoveTimeMs:        0,
+		MobilityWeight:    1.0,
+		HangingWeight:     0.5,
+		BishopPairBonus:   5,
+		OutpostBonus:      3,
+		CheckBonus:        2,
+		ThreatBonus:       1,
+		Tempo:             1,
+		NStep:             1,
+		Lambda:            0,
+		LearningAlgo:      algoQLearning,
+		LearningEnabled:   true,
+		DoubleQ:           false,
+		Augment:           false,
+		ReplaySize:        10000,
+		ReplayBatch:       64,
+		MaxStates:         1000000,
+		Gzip:              true,
+		Journal:           true,
+		JournalMaxMB:      64,
+		AutosaveSecs:      60,
+		UseBook:           true,
+		CORSOrigin:        "*",
+		RateLimit:         5,
+		RateBurst:         10,
+		AdminRateLimit:    0.2,
+		AdminRateBurst:    3,
+		TrustProxy:        false,
+		AdminToken:        "",
+		RepetitionPenalty: 20,
+		Contempt:          15,
+		EloK:              32,
+		ProgressEvery:     50,
+	}
+}
+
+// Q값 갱신 방식 (Config.LearningAlgo)
+const (
+	algoQLearning = "qlearning" // off-policy: max_a' Q(s',a')
+	algoSARSA     = "sarsa"     // on-policy: Q(s',a'), a'는 실제로 둔 수
+)
+
+// 판 수에 따라 값을 줄이는 방식 (Config.DecaySchedule)
+const (
+	scheduleNone        = "none"        // 줄이지 않음
+	scheduleLinear      = "linear"      // 판마다 decay만큼 뺌
+	scheduleExponential = "exponential" // 판마다 decay를 곱함
+)
+
+// games판을 둔 뒤의 값입니다. floor 아래로는 내려가지 않으며,
+// 시작값이 이미 floor보다 작으면 시작값을 그대로 씁니다.
+func decayed(start, floor, decay float64, games int, schedule string) float64 {
+	v := start
+	switch schedule {
+	case scheduleLinear:
+		v = start - decay*float64(games)
+	case scheduleExponential:
+		v = start * math.Pow(decay, float64(games))
+	}
+	return math.Max(v, math.Min(floor, start))
+}
+
+// games판을 둔 뒤 실제로 쓰는 탐색 확률
+func (c Config) effectiveEpsilon(games int) float64 {
+	return decayed(c.Epsilon, c.EpsilonMin, c.EpsilonDecay, games, c.DecaySchedule)
+}
+
+// games판을 둔 뒤 실제로 쓰는 학습률 (adaptive_alpha면 하한)
+func (c Config) effectiveAlpha(games int) float64 {
+	return decayed(c.Alpha, c.AlphaMin, c.AlphaDecay, games, c.DecaySchedule)
+}
+
+// 설정 파일을 기본값 위에 덮어써서 읽습니다.
+func loadConfig(path string) Config {
+	cfg := defaultConfig()
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("설정 파일을 읽지 못해 기본값을 씁니다", "path", path, "err", err)
+		}
+		return cfg
+	}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		slog.Warn("설정 파일 형식 오류", "path", path, "err", err)
+	}
+	return cfg
+}
+
+// 탐색 깊이 설정의 상한. 깊이 하나에 시간이 몇 배씩 늘어납니다.
+const maxSearchDepth = 8
+
+// 설정 값의 범위를 검사합니다. 처음 어긋난 항목을 오류로 돌려줍니다.
+func (c Config) validate() error {
+	unit := map[string]float64{
+		"epsilon": c.Epsilon, "epsilon_min": c.EpsilonMin, "alpha": c.Alpha, "alpha_min": c.AlphaMin,
+		"gamma": c.Gamma, "decay": c.Decay, "lambda": c.Lambda,
+	}
+	for name, v := range unit {
+		if v < 0 || v > 1 || math.IsNaN(v) {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	nonNegative := map[string]float64{
+		"epsilon_decay": c.EpsilonDecay, "alpha_decay": c.AlphaDecay, "temperature": c.Temperature,
+		"mobility_weight": c.MobilityWeight, "hanging_weight": c.HangingWeight, "elo_k": c.EloK,
+		"move_time_ms": float64(c.MoveTimeMs), "replay_batch": float64(c.ReplayBatch), "max_states": float64(c.MaxStates),
+		"repetition_penalty": c.RepetitionPenalty, "contempt": c.Contempt,
+		"check_bonus": c.CheckBonus, "threat_bonus": c.ThreatBonus, "tempo": c.Tempo,
+		"q_scale": c.QScale, "q_weight": c.QWeight, "eval_weight": c.EvalWeight,
+		"progress_every": float64(c.ProgressEvery), "journal_max_mb": float64(c.JournalMaxMB),
+	}
+	for name, v := range nonNegative {
+		if v < 0 || math.IsNaN(v) {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if c.SearchDepth < 1 || c.SearchDepth > maxSearchDepth {
+		return fmt.Errorf("search_depth must be between 1 and %d", maxSearchDepth)
+	}
+	if c.NStep < 1 {
+		return errors.New("n_step must be at least 1")
+	}
+	switch c.SelectionMode {
+	case selectGreedy, selectEpsilon, selectSoftmax:
+	default:
+		return fmt.Errorf("unknown selection_mode %q (greedy, epsilon, softmax)", c.SelectionMode)
+	}
+	switch c.DecaySchedule {
+	case scheduleNone, scheduleLinear, scheduleExponential:
+	default:
+		return fmt.Errorf("unknown decay_schedule %q (none, linear, exponential)", c.DecaySchedule)
+	}
+	switch c.LearningAlgo {
+	case algoQLearning, algoSARSA:
+	default:
+		return fmt.Errorf("unknown learning_algo %q (qlearning, sarsa)", c.LearningAlgo)
+	}
+	return nil
+}
+
+// 서버를 다시 시작해야 반영되는 설정 (POST /config로 바꿀 수 없음)
+// admin_token은 관리 권한 자체를 바꾸므로 환경 변수나 설정 파일로만 정합니다.
+func restartOnlyChanges(old, new Config) []string {
+	var changed []string
+	check := func(name string, differs bool) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	check("cors_origin", old.CORSOrigin != new.CORSOrigin)
+	check("rate_limit", old.RateLimit != new.RateLimit)
+	check("rate_burst", old.RateBurst != new.RateBurst)
+	check("admin_rate_limit", old.AdminRateLimit != new.AdminRateLimit)
+	check("admin_rate_burst", old.AdminRateBurst != new.AdminRateBurst)
+	check("trust_proxy", old.TrustProxy != new.TrustProxy)
+	check("admin_token", old.AdminToken != new.AdminToken)
+	check("autosave_seconds", old.AutosaveSecs != new.AutosaveSecs)
+	check("replay_size", old.ReplaySize != new.ReplaySize)
+	return changed
+}
+
+// 두 설정에서 값이 다른 항목을 "이름: 이전 -> 새 값" 형태로 모읍니다. (로그용)
+func configChanges(old, new Config) []string {
+	var a, b map[string]interface{}
+	oldJSON, _ := json.Marshal(old)
+	newJSON, _ := json.Marshal(new)
+	json.Unmarshal(oldJSON, &a)
+	json.Unmarshal(newJSON, &b)
+	var changes []string
+	for key, v := range b {
+		if !reflect.DeepEqual(a[key], v) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, a[key], v))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
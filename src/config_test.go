@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestDecayReachesFloor(t *testing.T) {
+	for _, schedule := range []string{scheduleLinear, scheduleExponential} {
+		cfg := defaultConfig()
+		cfg.DecaySchedule = schedule
+		cfg.Epsilon, cfg.EpsilonMin = 0.5, 0.05
+		cfg.Alpha, cfg.AlphaMin = 0.3, 0.01
+		cfg.EpsilonDecay, cfg.AlphaDecay = 0.001, 0.001
+		if schedule == scheduleExponential {
+			cfg.EpsilonDecay, cfg.AlphaDecay = 0.99, 0.99
+		}
+
+		if got := cfg.effectiveEpsilon(0); got != 0.5 {
+			t.Errorf("%s: epsilon at 0 games = %v, want 0.5", schedule, got)
+		}
+		mid := cfg.effectiveEpsilon(100)
+		if mid >= 0.5 || mid <= 0.05 {
+			t.Errorf("%s: epsilon at 100 games = %v, want between floor and start", schedule, mid)
+		}
+		if got := cfg.effectiveEpsilon(100000); got != 0.05 {
+			t.Errorf("%s: epsilon after many games = %v, want floor 0.05", schedule, got)
+		}
+		if got := cfg.effectiveAlpha(100000); got != 0.01 {
+			t.Errorf("%s: alpha after many games = %v, want floor 0.01", schedule, got)
+		}
+	}
+}
+
+func TestNoDecayKeepsStartValue(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DecaySchedule = scheduleNone
+	cfg.Epsilon, cfg.EpsilonMin, cfg.EpsilonDecay = 0.2, 0.05, 0.5
+	if got := cfg.effectiveEpsilon(100000); got != 0.2 {
+		t.Errorf("epsilon = %v, want 0.2", got)
+	}
+}
+
+func TestStatsShowDecayedEpsilon(t *testing.T) {
+	newTestAI(t)
+	ai.DecaySchedule = scheduleExponential
+	ai.Epsilon, ai.EpsilonMin, ai.EpsilonDecay = 0.5, 0.05, 0.9
+	ai.GameCount = 10000
+
+	if body := getStats(t); body["epsilon"] != 0.05 {
+		t.Errorf("stats epsilon = %v, want floor 0.05", body["epsilon"])
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "math/rand"
+
+// 학습에 쓰는 Q값 표 묶음입니다. a는 ai.QTable이고, b는 double_q일 때만 쓰는 두 번째 표(QB)입니다.
+// dirty가 있으면 갱신한 상태를 표시합니다. (공유 표만. 저장 로그에 씀)
+type qTables struct {
+	a, b  map[string]map[string]float64
+	dirty map[string]struct{}
+}
+
+// 공유 Q값 표 묶음. ai.mu 잠금 아래에서 사용합니다.
+func sharedTables() qTables {
+	return qTables{a: ai.QTable, b: ai.QB, dirty: ai.dirty}
+}
+
+// 수 선택에 쓸 한 상태의 수별 Q값(복사본)입니다. double_q면 두 표의 합입니다.
+// ai.mu 잠금 아래에서 호출합니다.
+func qValues(state string) map[string]float64 {
+	row := make(map[string]float64, len(ai.QTable[state]))
+	for move, q := range ai.QTable[state] {
+		row[move] = q
+	}
+	if ai.DoubleQ {
+		for move, q := range ai.QB[state] {
+			row[move] += q
+		}
+	}
+	return row
+}
+
+// 한 상태에서 Q값이 가장 높은 수입니다. (학습된 수가 없으면 "")
+func argmaxQ(actions map[string]float64) string {
+	best, bestQ := "", 0.0
+	for move, q := range actions {
+		if best == "" || q > bestQ {
+			best, bestQ = move, q
+		}
+	}
+	return best
+}
+
+// 이번 갱신에서 고칠 표와 다음 상태를 평가할 표를 고릅니다.
+// double_q면 둘 중 하나를 무작위로 고치고, 다음 상태의 최선 수는 고치는 표로 고르되
+// 그 값은 다른 표에서 읽어 max 연산의 과대평가를 줄입니다.
+func (q qTables) pick(r *rand.Rand) (update, eval map[string]map[string]float64) {
+	if ai.DoubleQ && r.Intn(2) == 1 {
+		return q.b, q.a
+	}
+	if ai.DoubleQ {
+		return q.a, q.b
+	}
+	return q.a, q.a
+}
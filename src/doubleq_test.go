@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDoubleQTablesDivergeAndBothSelect(t *testing.T) {
+	newTestAI(t)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment, ai.Lambda, ai.NStep = true, false, false, 0, 1
+	r := rand.New(rand.NewSource(4))
+	tables := sharedTables()
+	history := []string{"s|e2e4|0|t", "t|e7e5|0|"}
+	for i := 0; i < 20; i++ {
+		learnInto(tables, ai.Visits, history, 10, 0.3, r)
+	}
+
+	a, b := ai.QTable["s"]["e2e4"], ai.QB["s"]["e2e4"]
+	if a == 0 || b == 0 {
+		t.Fatalf("both tables should have learned: a=%v b=%v", a, b)
+	}
+	if a == b {
+		t.Errorf("tables did not diverge: a=b=%v", a)
+	}
+	// 수 선택에는 두 표의 합을 씁니다.
+	if got := qValues("s")["e2e4"]; got != a+b {
+		t.Errorf("qValues = %v, want a+b = %v", got, a+b)
+	}
+	ai.DoubleQ = false
+	if got := qValues("s")["e2e4"]; got != a {
+		t.Errorf("single-table qValues = %v, want a = %v", got, a)
+	}
+}
+
+func TestDoubleQTablesPersist(t *testing.T) {
+	newTestAI(t)
+	ai.Journal, ai.DoubleQ = false, true
+	ai.QTable["s"] = map[string]float64{"e2e4": 1}
+	ai.QB["s"] = map[string]float64{"e2e4": 2}
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	ai.QTable, ai.QB = nil, nil
+	loadFromFile()
+	if ai.QTable["s"]["e2e4"] != 1 || ai.QB["s"]["e2e4"] != 2 {
+		t.Errorf("after reload: a=%v b=%v", ai.QTable["s"], ai.QB["s"])
+	}
+}
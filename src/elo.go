@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// 처음 시작하는 AI와 레이팅을 알려 주지 않은 상대의 기본 Elo 레이팅
+const defaultRating = 1200
+
+// rating인 쪽이 opponent를 상대로 얻을 것으로 기대되는 점수 (0~1)
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// 한 판의 결과(score: 승 1, 무 0.5, 패 0)로 레이팅을 갱신합니다. R' = R + K(S - E)
+func updateElo(rating, opponent, score, k float64) float64 {
+	return rating + k*(score-expectedScore(rating, opponent))
+}
+
+// AI 쪽에서 본 한 판의 점수. 결과는 terminalReward와 같이 최종 FEN으로 다시 판정합니다.
+func resultScore(result, fen string, aiColor chess.Color) float64 {
+	result, _ = judgeResult(result, fen)
+	switch result {
+	case aiColor.Name():
+		return 1
+	case "Draw":
+		return 0.5
+	}
+	return 0
+}
+
+const (
+	resultHistory      = 1000 // 최근 결과를 남겨 두는 판 수
+	defaultStatsWindow = 100  // /stats의 최근 승률 기본 구간
+)
+
+// 최근 window판의 승/무/패 집계 (AI 관점)
+type recentStats struct {
+	Window   int     `json:"window"`
+	Games    int     `json:"games"` // 아직 window판을 채우지 못했으면 window보다 작음
+	Wins     int     `json:"wins"`
+	Draws    int     `json:"draws"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+	DrawRate float64 `json:"draw_rate"`
+	LossRate float64 `json:"loss_rate"`
+}
+
+// 끝난 대국의 결과(score: 승 1, 무 0.5, 패 0)를 ai.Results 끝에 'W'·'D'·'L'로 덧붙이고
+// resultHistory판을 넘는 오래된 결과는 버립니다. ai.mu 잠금 아래에서 호출합니다.
+func recordResult(score float64) {
+	c := "D"
+	switch score {
+	case 1:
+		c = "W"
+	case 0:
+		c = "L"
+	}
+	ai.Results += c
+	if n := len(ai.Results); n > resultHistory {
+		ai.Results = ai.Results[n-resultHistory:]
+	}
+}
+
+// 최근 결과(ai.Results를 복사한 값) 중 마지막 window판을 집계합니다.
+func recentResults(results string, window int) recentStats {
+	recent := results
+	if len(recent) > window {
+		recent = recent[len(recent)-window:]
+	}
+	s := recentStats{Window: window, Games: len(recent)}
+	for _, c := range recent {
+		switch c {
+		case 'W':
+			s.Wins++
+		case 'D':
+			s.Draws++
+		default:
+			s.Losses++
+		}
+	}
+	if s.Games > 0 {
+		n := float64(s.Games)
+		s.WinRate, s.DrawRate, s.LossRate = float64(s.Wins)/n, float64(s.Draws)/n, float64(s.Losses)/n
+	}
+	return s
+}
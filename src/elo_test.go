@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestEloUpdate(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if got := expectedScore(1200, 1200); got != 0.5 {
+		t.Errorf("expected score for equal ratings = %v, want 0.5", got)
+	}
+	// 400점 높은 상대에게는 1/(1+10)을 기대합니다.
+	if got := expectedScore(1200, 1600); !near(got, 1.0/11) {
+		t.Errorf("expected score vs +400 = %v, want 1/11", got)
+	}
+	cases := []struct{ rating, opponent, score, want float64 }{
+		{1200, 1200, 1, 1216},
+		{1200, 1200, 0, 1184},
+		{1200, 1200, 0.5, 1200},
+		{1200, 1600, 1, 1200 + 32*10.0/11},
+		{1600, 1200, 0, 1600 - 32*10.0/11},
+	}
+	for _, c := range cases {
+		if got := updateElo(c.rating, c.opponent, c.score, 32); !near(got, c.want) {
+			t.Errorf("updateElo(%v, %v, %v) = %v, want %v", c.rating, c.opponent, c.score, got, c.want)
+		}
+	}
+	// 두 선수가 주고받는 점수의 합은 0입니다.
+	a, b := updateElo(1500, 1300, 1, 32), updateElo(1300, 1500, 0, 32)
+	if !near(a-1500, 1300-b) {
+		t.Errorf("rating change not zero-sum: +%v vs -%v", a-1500, 1300-b)
+	}
+}
+
+func TestFinishGameUpdatesRating(t *testing.T) {
+	newTestAI(t)
+	// 백이 체크메이트된 포지션 (fool's mate). 보낸 결과와 달라도 FEN으로 다시 판정합니다.
+	fen := "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	if got := resultScore("White", fen, chess.Black); got != 1 {
+		t.Errorf("resultScore for the mating side = %v, want 1", got)
+	}
+	finishGame("Black", fen, map[string]chess.Color{"s": chess.Black}, 1200, false)
+	if ai.Rating != 1216 {
+		t.Errorf("rating after a win = %v, want 1216", ai.Rating)
+	}
+	if ai.Results != "W" || ai.GameCount != 0 {
+		t.Errorf("results=%q game_count=%d, want W and 0 (learning off)", ai.Results, ai.GameCount)
+	}
+}
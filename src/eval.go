@@ -0,0 +1,816 @@
+package main
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// [핵심] 기물별 가치를 설정에서 읽습니다.
+func getPieceValue(p chess.Piece) float64 {
+	values := ai.PieceValues
+	switch p.Type() {
+	case chess.Pawn:
+		return values.Pawn
+	case chess.Knight:
+		return values.Knight
+	case chess.Bishop:
+		return values.Bishop
+	case chess.Rook:
+		return values.Rook
+	case chess.Queen:
+		return values.Queen
+	case chess.King:
+		return values.King
+	}
+	return 0
+}
+
+// 기물-칸 표(PST)의 점수를 기물 가치 단위(폰=10)로 맞추는 배율
+const pstWeight = 0.1
+
+// 기물-칸 표. 백 기준이며 인덱스 0이 a8, 63이 h1입니다. (폰=100 단위)
+// 아래 표들은 미들게임용입니다.
+var (
+	pawnTable = [64]float64{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = [64]float64{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = [64]float64{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookTable = [64]float64{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	queenTable = [64]float64{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingTable = [64]float64{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+
+	pieceSquareTables = map[chess.PieceType]*[64]float64{
+		chess.Pawn:   &pawnTable,
+		chess.Knight: &knightTable,
+		chess.Bishop: &bishopTable,
+		chess.Rook:   &rookTable,
+		chess.Queen:  &queenTable,
+		chess.King:   &kingTable,
+	}
+)
+
+// 엔드게임용 표. 폰은 전진할수록, 왕은 중앙으로 나올수록 좋습니다.
+var (
+	pawnEndTable = [64]float64{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		80, 80, 80, 80, 80, 80, 80, 80,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		30, 30, 30, 30, 30, 30, 30, 30,
+		20, 20, 20, 20, 20, 20, 20, 20,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	kingEndTable = [64]float64{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+
+	// 엔드게임 표가 없는 기물은 미들게임 표를 그대로 씁니다.
+	endgameTables = map[chess.PieceType]*[64]float64{
+		chess.Pawn:   &pawnEndTable,
+		chess.Knight: &knightTable,
+		chess.Bishop: &bishopTable,
+		chess.Rook:   &rookTable,
+		chess.Queen:  &queenTable,
+		chess.King:   &kingEndTable,
+	}
+)
+
+// 게임 단계 계산용 기물 가중치. 처음 배치(총 24)일 때 1, 기물이 모두 사라지면 0입니다.
+// 나이트·비숍 1, 룩 2, 퀸 4, 폰과 왕은 0으로 셉니다.
+var phaseWeights = map[chess.PieceType]float64{
+	chess.Knight: 1,
+	chess.Bishop: 1,
+	chess.Rook:   2,
+	chess.Queen:  4,
+}
+
+const totalPhase = 24.0
+
+// gamePhase는 남은 기물로 게임 단계를 0(엔드게임)~1(미들게임) 사이 값으로 반환합니다.
+func gamePhase(pos *chess.Position) float64 {
+	phase := 0.0
+	board := pos.Board()
+	for i := 0; i < 64; i++ {
+		if p := board.Piece(chess.Square(i)); p != chess.NoPiece {
+			phase += phaseWeights[p.Type()]
+		}
+	}
+	if phase > totalPhase {
+		phase = totalPhase // 승격으로 기물이 늘어난 경우
+	}
+	return phase / totalPhase
+}
+
+// 표에서 칸의 값을 읽습니다. 흑은 표를 위아래로 뒤집어 읽습니다.
+func tableValue(table *[64]float64, p chess.Piece, sq chess.Square) float64 {
+	if table == nil {
+		return 0
+	}
+	rank, file := int(sq.Rank()), int(sq.File())
+	if p.Color() == chess.White {
+		rank = 7 - rank
+	}
+	return table[rank*8+file]
+}
+
+// 칸에 놓인 기물의 위치 점수를 게임 단계에 따라 미들게임/엔드게임 표 사이에서 보간합니다.
+func pieceSquareValue(p chess.Piece, sq chess.Square, phase float64) float64 {
+	mid := tableValue(pieceSquareTables[p.Type()], p, sq)
+	end := tableValue(endgameTables[p.Type()], p, sq)
+	return (mid*phase + end*(1-phase)) * pstWeight
+}
+
+// [평가] 보드 상태의 점수를 둘 차례인 쪽(pos.Turn()) 관점으로 계산합니다. 양수면 둘 차례인 쪽이 유리합니다.
+// 네가맥스처럼 한 수 내려간 포지션의 점수는 부호를 뒤집어 씁니다.
+// 둘 차례인 쪽의 템포 가점이 들어 있어, 같은 배치라도 차례가 바뀌면 점수가 2×tempo만큼 달라집니다.
+func evaluate(pos *chess.Position) float64 {
+	sides := evaluateSides(pos)
+	return sides[pos.Turn()].Total - sides[pos.Turn().Other()].Total
+}
+
+// 차례와 관계없이 정해진 색(종료 보상의 AI 색, 기보 리뷰의 백 등) 관점의 점수입니다.
+func evaluateBoard(pos *chess.Position, color chess.Color) float64 {
+	if color == pos.Turn() {
+		return evaluate(pos)
+	}
+	return -evaluate(pos)
+}
+
+// 한 색의 평가 항목별 점수입니다. evaluate는 둘 차례인 쪽과 상대의 Total 차이입니다.
+type sideEval struct {
+	Material      float64 `json:"material"`
+	PieceSquare   float64 `json:"piece_square"`
+	Mobility      float64 `json:"mobility"`
+	PawnStructure float64 `json:"pawn_structure"`
+	KingSafety    float64 `json:"king_safety"`
+	Hanging       float64 `json:"hanging"`
+	Rooks         float64 `json:"rooks"`
+	BishopPair    float64 `json:"bishop_pair"`
+	Outposts      float64 `json:"outposts"`
+	Center        float64 `json:"center"`
+	Threats       float64 `json:"threats"`
+	Tempo         float64 `json:"tempo"`
+	Endgame       float64 `json:"endgame"`
+	Total         float64 `json:"total"`
+}
+
+// 두 색의 평가 항목을 따로 계산합니다. chess.Color 값(White, Black)으로 꺼내 씁니다.
+func evaluateSides(pos *chess.Position) [3]sideEval {
+	var sides [3]sideEval
+	board := pos.Board()
+	phase := gamePhase(pos)
+	var pawnFiles [3][8]int // 색별·파일별 폰 수
+	var rooks, knights [3][]chess.Square
+	var kings [3]chess.Square
+	var bishops [3]int
+	pawnsOnly := true // 왕과 폰만 남았는지
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		p := board.Piece(sq)
+		if p != chess.NoPiece {
+			sides[p.Color()].Material += getPieceValue(p)
+			sides[p.Color()].PieceSquare += pieceSquareValue(p, sq, phase)
+		}
+		switch p.Type() {
+		case chess.Pawn:
+			pawnFiles[p.Color()][sq.File()]++
+		case chess.Rook:
+			rooks[p.Color()] = append(rooks[p.Color()], sq)
+		case chess.Knight:
+			knights[p.Color()] = append(knights[p.Color()], sq)
+		case chess.Bishop:
+			bishops[p.Color()]++
+		case chess.King:
+			kings[p.Color()] = sq
+		}
+		if p != chess.NoPiece && p.Type() != chess.Pawn && p.Type() != chess.King {
+			pawnsOnly = false
+		}
+	}
+
+	cr := pos.CastleRights()
+	// 기동력·왕 주변 공격·걸린 기물 모두 보드를 한 번 훑은 공격 정보에서 구합니다.
+	attacked := attackMaps(board)
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		s := &sides[c]
+		s.Mobility = ai.MobilityWeight * float64(attacked.mobility[c])
+		s.PawnStructure = pawnStructure(board, c)
+		// 왕 안전은 기물이 많이 남아 있을수록 중요하므로 게임 단계를 곱합니다.
+		s.KingSafety = kingSafety(board, cr, c, attacked) * phase
+		// 방금 둔 쪽(차례가 아닌 쪽)의 걸린 기물은 상대가 다음 수에 잡을 수 있습니다.
+		if loss := hangingLoss(board, attacked, c); c != pos.Turn() && loss > 0 {
+			s.Hanging = -ai.HangingWeight * loss
+		}
+		s.Rooks = rookActivity(board, rooks[c], pawnFiles[c], pawnFiles[c.Other()])
+		if bishops[c] >= 2 {
+			s.BishopPair = ai.BishopPairBonus * openness(pawnFiles)
+		}
+		s.Outposts = ai.OutpostBonus * float64(knightOutposts(board, c, knights[c]))
+		// 중앙 장악은 오프닝에서 중요하므로 게임 단계를 곱합니다.
+		s.Center = centerControl(board, attacked, c) * phase
+		// 체크와 위협은 방금 둔 쪽만 받습니다. 상대가 받아낼 수 있는지는 탐색이 판단합니다.
+		if c != pos.Turn() {
+			if attacked.count[c][kings[c.Other()]] > 0 {
+				s.Threats += ai.CheckBonus
+			}
+			s.Threats += ai.ThreatBonus * float64(threatCount(board, attacked, c))
+		} else {
+			// 둘 차례인 쪽은 한 수를 먼저 둘 수 있습니다.
+			s.Tempo = ai.Tempo
+		}
+		// 엔드게임 항목은 기물이 줄어들수록(게임 단계가 0에 가까울수록) 커집니다.
+		if phase < 1 {
+			ahead := sides[c].Material > sides[c.Other()].Material
+			s.Endgame = endgameBonus(board, c, kings, ahead && pawnsOnly) * (1 - phase)
+		}
+		s.Total = s.Material + s.PieceSquare + s.Mobility + s.PawnStructure + s.KingSafety + s.Hanging + s.Rooks +
+			s.BishopPair + s.Outposts + s.Center + s.Threats + s.Tempo + s.Endgame
+	}
+	return sides
+}
+
+// 폰 구조 점수 (폰=10 단위)
+const (
+	doubledPawnPenalty  = 2.0 // 같은 파일에 겹친 폰 1개당
+	isolatedPawnPenalty = 1.5 // 이웃 파일에 같은 편 폰이 없는 폰 1개당
+	passedPawnBonus     = 2.0 // 통과한 폰이 시작 랭크에서 한 칸 나아갈 때마다
+)
+
+// 파일별 같은 편(own)·상대(enemy) 폰의 랭크 목록입니다.
+func pawnRanks(board *chess.Board, color chess.Color) (own, enemy [8][]int) {
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		p := board.Piece(sq)
+		if p.Type() != chess.Pawn {
+			continue
+		}
+		if p.Color() == color {
+			own[sq.File()] = append(own[sq.File()], int(sq.Rank()))
+		} else {
+			enemy[sq.File()] = append(enemy[sq.File()], int(sq.Rank()))
+		}
+	}
+	return own, enemy
+}
+
+// 한 색의 폰 구조 점수를 계산합니다: 겹친 폰·고립된 폰 감점, 통과한 폰 가점.
+func pawnStructure(board *chess.Board, color chess.Color) float64 {
+	own, enemy := pawnRanks(board, color)
+
+	score := 0.0
+	for file := 0; file < 8; file++ {
+		if n := len(own[file]); n > 1 {
+			score -= doubledPawnPenalty * float64(n-1)
+		}
+		for _, rank := range own[file] {
+			if isIsolated(own, file) {
+				score -= isolatedPawnPenalty
+			}
+			if isPassed(enemy, file, rank, color) {
+				advance := rank - 1
+				if color == chess.Black {
+					advance = 6 - rank
+				}
+				score += passedPawnBonus * float64(advance)
+			}
+		}
+	}
+	return score
+}
+
+// 엔드게임 점수 (폰=10 단위). evaluateSides에서 (1 - 게임 단계)를 곱합니다.
+const (
+	passedPawnEndBonus = 0.5 // 통과한 폰이 나아간 칸 수의 제곱에 곱함
+	kingPasserWeight   = 1.0 // 통과한 폰 앞 칸에 상대 왕보다 한 칸 가까울 때마다
+	kingTropismWeight  = 1.0 // 폰 엔딩에서 앞선 쪽 왕이 상대 왕에 한 칸 가까울 때마다
+)
+
+// 한 색의 엔드게임 점수: 많이 나아간 통과한 폰 가점(승격에 가까울수록 크게),
+// 통과한 폰 앞 칸에 같은 편 왕이 상대 왕보다 가까우면 가점(멀면 감점).
+// chase가 true면(왕과 폰만 남았고 기물이 앞선 쪽) 상대 왕에 다가갈수록 가점을 줍니다.
+func endgameBonus(board *chess.Board, color chess.Color, kings [3]chess.Square, chase bool) float64 {
+	own, enemy := pawnRanks(board, color)
+	forward := 1
+	if color == chess.Black {
+		forward = -1
+	}
+	score := 0.0
+	for file := 0; file < 8; file++ {
+		for _, rank := range own[file] {
+			if !isPassed(enemy, file, rank, color) {
+				continue
+			}
+			advance := rank - 1
+			if color == chess.Black {
+				advance = 6 - rank
+			}
+			score += passedPawnEndBonus * float64(advance*advance)
+			if next := rank + forward; next >= 0 && next <= 7 {
+				front := chess.NewSquare(chess.File(file), chess.Rank(next))
+				score += kingPasserWeight * float64(squareDistance(kings[color.Other()], front)-squareDistance(kings[color], front))
+			}
+		}
+	}
+	if chase {
+		score += kingTropismWeight * float64(7-squareDistance(kings[color], kings[color.Other()]))
+	}
+	return score
+}
+
+// 두 칸 사이의 왕 걸음 수 (체비셰프 거리)
+func squareDistance(a, b chess.Square) int {
+	return max(abs(int(a.File())-int(b.File())), abs(int(a.Rank())-int(b.Rank())))
+}
+
+// 룩 활동 점수 (폰=10 단위)
+const (
+	rookOpenFileBonus     = 2.5 // 양쪽 폰이 모두 없는 파일의 룩
+	rookHalfOpenFileBonus = 1.0 // 같은 편 폰만 없는 파일의 룩
+	connectedRooksBonus   = 1.5 // 같은 랭크·파일에서 사이에 기물 없이 서로 지키는 두 룩
+)
+
+// 한 색 룩의 점수: 열린·반쯤 열린 파일 가점, 연결된 룩 가점.
+// own, enemy는 파일별 같은 편·상대 폰 수입니다.
+func rookActivity(board *chess.Board, rooks []chess.Square, own, enemy [8]int) float64 {
+	score := 0.0
+	for _, sq := range rooks {
+		switch f := sq.File(); {
+		case own[f] == 0 && enemy[f] == 0:
+			score += rookOpenFileBonus
+		case own[f] == 0:
+			score += rookHalfOpenFileBonus
+		}
+	}
+	for i := 0; i < len(rooks); i++ {
+		for j := i + 1; j < len(rooks); j++ {
+			if clearBetween(board, rooks[i], rooks[j]) {
+				score += connectedRooksBonus
+			}
+		}
+	}
+	return score
+}
+
+// 중앙 장악 점수 (폰=10 단위). 넓은 중앙(c3~f6의 나머지 칸)은 절반입니다.
+const (
+	centerPawnBonus   = 3.0 // 중앙 네 칸(d4, e4, d5, e5)에 있는 폰
+	centerPieceBonus  = 1.0 // 중앙 네 칸에 있는 폰 외의 기물 (왕 제외)
+	centerAttackBonus = 0.5 // 중앙 네 칸을 공격(지킴 포함)하는 기물 1개당
+)
+
+// 한 색의 중앙 장악 점수: 중앙 칸을 차지하거나 공격하는 폰·기물 가점.
+// attacked는 attackMaps로 구한 공격 정보입니다.
+func centerControl(board *chess.Board, attacked *attackMap, color chess.Color) float64 {
+	score := 0.0
+	for f := 2; f <= 5; f++ {
+		for r := 2; r <= 5; r++ {
+			weight := 0.5
+			if f >= 3 && f <= 4 && r >= 3 && r <= 4 {
+				weight = 1
+			}
+			sq := chess.NewSquare(chess.File(f), chess.Rank(r))
+			switch p := board.Piece(sq); {
+			case p.Color() != color || p.Type() == chess.King:
+			case p.Type() == chess.Pawn:
+				score += weight * centerPawnBonus
+			default:
+				score += weight * centerPieceBonus
+			}
+			score += weight * centerAttackBonus * float64(attacked.count[color][sq])
+		}
+	}
+	return score
+}
+
+// 비숍 쌍 가점에 곱하는 값. 폰이 모두 있으면 1, 폰이 줄어 판이 열릴수록 커져 폰이 없으면 1.5입니다.
+func openness(pawnFiles [3][8]int) float64 {
+	pawns := 0
+	for f := 0; f < 8; f++ {
+		pawns += pawnFiles[chess.White][f] + pawnFiles[chess.Black][f]
+	}
+	return 1 + 0.5*float64(max(16-pawns, 0))/16
+}
+
+// 전초기지에 있는 나이트 수. 상대 진영(상대 쪽 절반의 앞 세 랭크)에서 같은 편 폰이 지키고,
+// 이웃 파일의 상대 폰이 앞으로 나와도 공격할 수 없는 칸입니다.
+func knightOutposts(board *chess.Board, color chess.Color, knights []chess.Square) int {
+	own, enemy := pawnRanks(board, color)
+	forward := 1 // 폰이 나아가는 랭크 방향
+	if color == chess.Black {
+		forward = -1
+	}
+	n := 0
+	for _, sq := range knights {
+		f, r := int(sq.File()), int(sq.Rank())
+		// 자기 쪽에서 센 4~6랭크 (백 4~6, 흑 5~3)
+		rel := r
+		if color == chess.Black {
+			rel = 7 - r
+		}
+		if rel < 3 || rel > 5 {
+			continue
+		}
+		protected, attackable := false, false
+		for _, df := range []int{-1, 1} {
+			if f+df < 0 || f+df > 7 {
+				continue
+			}
+			for _, pr := range own[f+df] {
+				if pr == r-forward {
+					protected = true
+				}
+			}
+			// 상대 폰은 반대 방향으로 나아가므로 나이트보다 앞쪽(상대 진영 쪽)에 있으면 언젠가 공격할 수 있습니다.
+			for _, er := range enemy[f+df] {
+				if (er-r)*forward > 0 {
+					attackable = true
+				}
+			}
+		}
+		if protected && !attackable {
+			n++
+		}
+	}
+	return n
+}
+
+// 두 칸이 같은 랭크나 파일에 있고 그 사이가 비어 있는지 봅니다.
+func clearBetween(board *chess.Board, a, b chess.Square) bool {
+	df, dr := int(b.File())-int(a.File()), int(b.Rank())-int(a.Rank())
+	if df != 0 && dr != 0 {
+		return false
+	}
+	steps := max(abs(df), abs(dr))
+	for k := 1; k < steps; k++ {
+		f := int(a.File()) + df/steps*k
+		r := int(a.Rank()) + dr/steps*k
+		if board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r))) != chess.NoPiece {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// 왕 안전 점수 (폰=10 단위)
+const (
+	pawnShieldBonus     = 1.5 // 왕 앞 두 랭크 안에 있는 같은 편 폰 1개당
+	kingAttackerPenalty = 2.0 // 왕 주변 칸을 공격하는 상대 기물 1개당
+	lostCastlePenalty   = 3.0 // 캐슬링 권리를 잃고 왕이 중앙 파일에 남은 경우
+)
+
+// 한 색 왕의 안전 점수를 계산합니다: 폰 방패 가점, 왕 주변을 노리는 상대 기물 감점.
+// attacked는 attackMaps로 구한 공격 정보입니다.
+func kingSafety(board *chess.Board, cr chess.CastleRights, color chess.Color, attacked *attackMap) float64 {
+	king := chess.NoSquare
+	for i := 0; i < 64; i++ {
+		if p := board.Piece(chess.Square(i)); p.Type() == chess.King && p.Color() == color {
+			king = chess.Square(i)
+			break
+		}
+	}
+	if king == chess.NoSquare {
+		return 0
+	}
+	kFile, kRank := int(king.File()), int(king.Rank())
+	forward := 1
+	if color == chess.Black {
+		forward = -1
+	}
+
+	score := 0.0
+	for f := kFile - 1; f <= kFile+1; f++ {
+		for step := 1; step <= 2; step++ {
+			r := kRank + forward*step
+			if f < 0 || f > 7 || r < 0 || r > 7 {
+				continue
+			}
+			p := board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+			if p.Type() == chess.Pawn && p.Color() == color {
+				score += pawnShieldBonus
+			}
+		}
+	}
+
+	// 왕 주변 8칸을 공격하는 상대 기물 수 (한 기물이 여러 칸을 노려도 한 번만 셉니다)
+	var attackers uint64
+	for _, d := range kingSteps {
+		f, r := kFile+d[0], kRank+d[1]
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			continue
+		}
+		attackers |= attacked.from[color.Other()][chess.NewSquare(chess.File(f), chess.Rank(r))]
+	}
+	score -= kingAttackerPenalty * float64(bits.OnesCount64(attackers))
+
+	homeRank := 0
+	if color == chess.Black {
+		homeRank = 7
+	}
+	canCastle := cr.CanCastle(color, chess.KingSide) || cr.CanCastle(color, chess.QueenSide)
+	if !canCastle && kFile >= 3 && kFile <= 4 && kRank == homeRank {
+		score -= lostCastlePenalty
+	}
+	return score
+}
+
+// 색별로 각 칸을 공격하는 기물 수와 그중 가장 싼 기물의 가치입니다.
+// from은 각 칸을 공격하는 기물들의 칸 비트마스크, mobility는 색별 유사 합법 수 개수입니다.
+type attackMap struct {
+	count    [3][64]int
+	cheapest [3][64]float64
+	from     [3][64]uint64
+	mobility [3]int
+}
+
+// 나이트·왕이 움직이는 방향과 비숍·룩이 미끄러지는 방향 (파일, 랭크)
+var (
+	knightSteps = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps   = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopRays  = [][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+	rookRays    = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	queenRays   = append(append([][2]int{}, bishopRays...), rookRays...)
+)
+
+// 보드의 공격 정보를 구합니다. 합법 수와 달리 같은 편 기물이 있는 칸(지키는 칸)도 세며,
+// 핀과 체크는 고려하지 않습니다. 기동력도 같은 순회에서 셉니다:
+// 폰은 전진할 수 있는 칸과 잡을 수 있는 칸, 나머지 기물은 같은 편 기물이 없는 공격 칸입니다.
+func attackMaps(board *chess.Board) *attackMap {
+	am := &attackMap{}
+	var from chess.Square
+	empty := func(f, r int) bool {
+		return f >= 0 && f <= 7 && r >= 0 && r <= 7 && board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r))) == chess.NoPiece
+	}
+	mark := func(c chess.Color, f, r int, value float64, pawn bool) bool {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return false
+		}
+		sq := chess.NewSquare(chess.File(f), chess.Rank(r))
+		if am.count[c][sq] == 0 || value < am.cheapest[c][sq] {
+			am.cheapest[c][sq] = value
+		}
+		am.count[c][sq]++
+		am.from[c][sq] |= 1 << uint(from)
+		target := board.Piece(sq)
+		if target == chess.NoPiece {
+			if !pawn {
+				am.mobility[c]++
+			}
+			return true
+		}
+		if target.Color() != c {
+			am.mobility[c]++
+		}
+		return false
+	}
+	for i := 0; i < 64; i++ {
+		from = chess.Square(i)
+		p := board.Piece(from)
+		if p == chess.NoPiece {
+			continue
+		}
+		c, value := p.Color(), getPieceValue(p)
+		f, r := int(from.File()), int(from.Rank())
+		var steps, rays [][2]int
+		switch p.Type() {
+		case chess.Pawn:
+			dr, start := 1, 1
+			if c == chess.Black {
+				dr, start = -1, 6
+			}
+			mark(c, f-1, r+dr, value, true)
+			mark(c, f+1, r+dr, value, true)
+			if empty(f, r+dr) {
+				am.mobility[c]++
+				if r == start && empty(f, r+2*dr) {
+					am.mobility[c]++
+				}
+			}
+		case chess.Knight:
+			steps = knightSteps
+		case chess.King:
+			steps = kingSteps
+		case chess.Bishop:
+			rays = bishopRays
+		case chess.Rook:
+			rays = rookRays
+		case chess.Queen:
+			rays = queenRays
+		}
+		for _, d := range steps {
+			mark(c, f+d[0], r+d[1], value, false)
+		}
+		for _, d := range rays {
+			for k := 1; mark(c, f+d[0]*k, r+d[1]*k, value, false); k++ {
+			}
+		}
+	}
+	return am
+}
+
+// [평가] 상대에게 잡힐 수 있는 기물(en prise) 중 가장 크게 잃는 값입니다.
+// 지키는 기물이 없으면 기물 가치 전부를, 지키고 있어도 더 싼 기물에게 공격받으면 그 차이를 잃습니다.
+// 상대는 한 수에 하나만 잡을 수 있으므로 합이 아니라 최댓값을 씁니다. (왕은 제외)
+func hangingLoss(board *chess.Board, am *attackMap, color chess.Color) float64 {
+	worst := 0.0
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		p := board.Piece(sq)
+		if p == chess.NoPiece || p.Color() != color || p.Type() == chess.King || am.count[color.Other()][sq] == 0 {
+			continue
+		}
+		loss := getPieceValue(p)
+		if am.count[color][sq] > 0 {
+			loss -= am.cheapest[color.Other()][sq]
+		}
+		if loss > worst {
+			worst = loss
+		}
+	}
+	return worst
+}
+
+// [평가] color가 공격하는 상대 기물 중 지키는 기물이 없거나 공격하는 기물보다 비싼 것의 수입니다. (왕은 제외)
+// 여러 개를 한꺼번에 노리는 포크일수록 커집니다.
+func threatCount(board *chess.Board, am *attackMap, color chess.Color) int {
+	n := 0
+	enemy := color.Other()
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		p := board.Piece(sq)
+		if p == chess.NoPiece || p.Color() != enemy || p.Type() == chess.King || am.count[color][sq] == 0 {
+			continue
+		}
+		if am.count[enemy][sq] == 0 || am.cheapest[color][sq] < getPieceValue(p) {
+			n++
+		}
+	}
+	return n
+}
+
+func isIsolated(own [8][]int, file int) bool {
+	for _, f := range []int{file - 1, file + 1} {
+		if f >= 0 && f < 8 && len(own[f]) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 앞쪽(같은 파일과 이웃 파일)에 상대 폰이 없으면 통과한 폰입니다.
+func isPassed(enemy [8][]int, file, rank int, color chess.Color) bool {
+	for f := file - 1; f <= file+1; f++ {
+		if f < 0 || f > 7 {
+			continue
+		}
+		for _, r := range enemy[f] {
+			if (color == chess.White && r > rank) || (color == chess.Black && r < rank) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 양쪽 모두 체크메이트할 기물이 없는지 봅니다.
+// 폰, 룩, 퀸이 없고 비숍·나이트가 합쳐 하나 이하이면 무승부입니다.
+func insufficientMaterial(pos *chess.Position) bool {
+	minors := 0
+	for _, p := range pos.Board().SquareMap() {
+		switch p.Type() {
+		case chess.Pawn, chess.Rook, chess.Queen:
+			return false
+		case chess.Bishop, chess.Knight:
+			minors++
+		}
+	}
+	return minors <= 1
+}
+
+// Position.MarshalBinary 형식: 보드 96바이트, 반수 1, 수 번호 2, 앙파상 칸 1, 플래그 1.
+// 라이브러리가 문서로 약속한 형식이 아니므로 flipTurn은 결과를 확인하고, 다르면 FEN으로 만듭니다.
+const (
+	posBinarySize     = 101
+	posBinaryEPByte   = 99
+	posBinaryFlagByte = 100
+	posFlagTurn       = 1 << 4 // 흑 차례
+	posFlagEnPassant  = 1 << 5 // 앙파상 칸 있음
+)
+
+// 차례만 바꾼 포지션을 만듭니다. 앙파상 칸은 의미가 없어지므로 지웁니다.
+// 문자열(FEN)을 거치지 않도록 바이너리 표현의 플래그만 고치고, 읽어 들인 포지션이 기대와 다르면
+// (라이브러리의 형식이 바뀐 경우) flipTurnFEN으로 만듭니다.
+func flipTurn(pos *chess.Position) *chess.Position {
+	data, err := pos.MarshalBinary()
+	if err != nil || len(data) != posBinarySize {
+		return flipTurnFEN(pos)
+	}
+	data[posBinaryFlagByte] ^= posFlagTurn
+	data[posBinaryFlagByte] &^= posFlagEnPassant
+	noSquare := chess.NoSquare
+	data[posBinaryEPByte] = byte(noSquare)
+	flipped := &chess.Position{}
+	if err := flipped.UnmarshalBinary(data); err != nil || !isFlipOf(flipped, pos) {
+		return flipTurnFEN(pos)
+	}
+	return flipped
+}
+
+// flipped가 pos에서 차례만 바꾸고 앙파상 칸을 지운 포지션인지 확인합니다.
+func isFlipOf(flipped, pos *chess.Position) bool {
+	if flipped.Turn() != pos.Turn().Other() || flipped.EnPassantSquare() != chess.NoSquare ||
+		flipped.CastleRights() != pos.CastleRights() || flipped.HalfMoveClock() != pos.HalfMoveClock() {
+		return false
+	}
+	a, b := flipped.Board(), pos.Board()
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if a.Piece(sq) != b.Piece(sq) {
+			return false
+		}
+	}
+	return true
+}
+
+// FEN을 거쳐 차례만 바꾼 포지션을 만듭니다. 라이브러리가 공개한 API만 씁니다.
+func flipTurnFEN(pos *chess.Position) *chess.Position {
+	fields := strings.Fields(pos.String())
+	if len(fields) < 4 {
+		return nil
+	}
+	fields[1] = pos.Turn().Other().String()
+	fields[3] = "-"
+	flipped := &chess.Position{}
+	if err := flipped.UnmarshalText([]byte(strings.Join(fields, " "))); err != nil {
+		return nil
+	}
+	return flipped
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/notnil/chess"
+)
+
+func TestCentralKnightBeatsCornerKnight(t *testing.T) {
+	newTestAI(t)
+	knight := chess.WhiteKnight
+	for _, phase := range []float64{0, 0.5, 1} {
+		center, corner := pieceSquareValue(knight, chess.D4, phase), pieceSquareValue(knight, chess.A1, phase)
+		if center <= corner {
+			t.Errorf("phase %v: knight d4 %v <= a1 %v", phase, center, corner)
+		}
+	}
+	// 흑은 표를 뒤집어 읽으므로 d5가 백의 d4와 같습니다.
+	if w, b := pieceSquareValue(knight, chess.D4, 1), pieceSquareValue(chess.BlackKnight, chess.D5, 1); w != b {
+		t.Errorf("mirrored knight values differ: white d4 %v, black d5 %v", w, b)
+	}
+
+	center := evaluate(mustPos(t, "4k3/8/8/8/3N4/8/8/4K3 w - - 0 1"))
+	corner := evaluate(mustPos(t, "4k3/8/8/8/8/8/8/N3K3 w - - 0 1"))
+	if center <= corner {
+		t.Errorf("evaluate: knight d4 %v <= a1 %v", center, corner)
+	}
+}
+
+func TestOpenPositionBeatsLockedOne(t *testing.T) {
+	newTestAI(t)
+	// 기물은 같고, 룩이 중앙에 나와 있는지(열림) 왕과 폰에 갇혀 있는지(막힘)만 다릅니다.
+	open := mustPos(t, "4k3/8/8/8/3R4/8/6PP/6K1 w - - 0 1")
+	locked := mustPos(t, "4k3/8/8/8/8/8/6PP/6KR w - - 0 1")
+	so, sl := evaluateSides(open), evaluateSides(locked)
+	if so[chess.White].Material != sl[chess.White].Material {
+		t.Fatalf("material differs: %v vs %v", so[chess.White].Material, sl[chess.White].Material)
+	}
+	if so[chess.White].Mobility <= sl[chess.White].Mobility {
+		t.Errorf("mobility: open %v <= locked %v", so[chess.White].Mobility, sl[chess.White].Mobility)
+	}
+	if e1, e2 := evaluate(open), evaluate(locked); e1 <= e2 {
+		t.Errorf("evaluate: open %v <= locked %v", e1, e2)
+	}
+
+	// 기동력 가중치를 끄면 기동력 항목이 사라집니다.
+	ai.MobilityWeight = 0
+	if m := evaluateSides(open)[chess.White].Mobility; m != 0 {
+		t.Errorf("mobility with zero weight = %v", m)
+	}
+}
+
+func TestPawnStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want float64 // 백 폰 구조 점수
+	}{
+		// 이웃 파일에 폰이 있고 상대 폰에 막혀 있어 감점도 가점도 없습니다.
+		{"healthy", "4k3/3ppp2/8/8/8/8/3PPP2/4K3 w - - 0 1", 0},
+		// e파일 겹친 폰 하나, 둘 다 고립, 상대 폰에 막힘
+		{"doubled", "4k3/4p3/8/8/8/4P3/4P3/4K3 w - - 0 1", -doubledPawnPenalty - 2*isolatedPawnPenalty},
+		// 6랭크(시작 랭크에서 네 칸)의 통과한 폰, 고립
+		{"passed", "4k3/8/3P4/8/8/8/8/4K3 w - - 0 1", 4*passedPawnBonus - isolatedPawnPenalty},
+	}
+	for _, tt := range tests {
+		board := mustPos(t, tt.fen).Board()
+		if got := pawnStructure(board, chess.White); got != tt.want {
+			t.Errorf("%s: pawnStructure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 흑 통과한 폰도 흑 쪽에서 센 거리만큼 가점을 받습니다.
+	board := mustPos(t, "4k3/8/8/8/8/3p4/8/4K3 w - - 0 1").Board()
+	if got, want := pawnStructure(board, chess.Black), 4*passedPawnBonus-isolatedPawnPenalty; got != want {
+		t.Errorf("black passed pawn = %v, want %v", got, want)
+	}
+}
+
+func TestCastledKingSaferThanExposed(t *testing.T) {
+	newTestAI(t)
+	castled := mustPos(t, "r2qk2r/ppp2ppp/2n5/8/8/2N5/PPP2PPP/R2Q1RK1 w kq - 0 10")
+	// 캐슬링 권리를 잃은 채 중앙에 남은 왕. 앞의 폰이 빠졌고 상대 퀸이 주변 칸을 노립니다.
+	exposed := mustPos(t, "r3k2r/ppp2ppp/2n5/8/8/2N1q3/PPP2PPP/R2QK2R w kq - 0 10")
+	c, e := evaluateSides(castled)[chess.White].KingSafety, evaluateSides(exposed)[chess.White].KingSafety
+	if c <= e {
+		t.Errorf("king safety: castled %v <= exposed %v", c, e)
+	}
+	if c <= 0 {
+		t.Errorf("castled king with full shield scored %v, want > 0", c)
+	}
+
+	// 엔드게임(기물이 모두 사라짐)에서는 왕 안전을 보지 않습니다.
+	if ks := evaluateSides(mustPos(t, "6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 40"))[chess.White].KingSafety; ks != 0 {
+		t.Errorf("endgame king safety = %v, want 0", ks)
+	}
+}
+
+func TestHangingQueenIsPenalized(t *testing.T) {
+	newTestAI(t)
+	queen := getPieceValue(chess.WhiteQueen)
+	// 백이 방금 퀸을 e6 폰이 잡을 수 있는 d5에 두었습니다. 지키는 기물이 없어 퀸 가치 전부를 잃습니다.
+	hanging := mustPos(t, "4k3/8/4p3/3Q4/8/8/8/4K3 b - - 0 1")
+	safe := mustPos(t, "4k3/8/4p3/8/8/3Q4/8/4K3 b - - 0 1")
+	if got := hangingLoss(hanging.Board(), attackMaps(hanging.Board()), chess.White); got != queen {
+		t.Errorf("hangingLoss = %v, want the full queen value %v", got, queen)
+	}
+	if got, want := evaluateSides(hanging)[chess.White].Hanging, -ai.HangingWeight*queen; got != want {
+		t.Errorf("hanging term = %v, want %v", got, want)
+	}
+	if evaluateSides(safe)[chess.White].Hanging != 0 {
+		t.Errorf("safe queen has hanging term %v", evaluateSides(safe)[chess.White].Hanging)
+	}
+	// 흑 차례 점수이므로 퀸이 걸려 있으면 흑에게 더 좋습니다.
+	if h, s := evaluate(hanging), evaluate(safe); h <= s {
+		t.Errorf("evaluate: hanging queen %v <= safe queen %v for the side to move", h, s)
+	}
+
+	// 폰이 지켜도 폰에게 공격받으면 퀸과 폰의 차이만큼 잃습니다.
+	defended := mustPos(t, "4k3/8/4p3/3Q4/4P3/8/8/4K3 b - - 0 1").Board()
+	if got, want := hangingLoss(defended, attackMaps(defended), chess.White), queen-getPieceValue(chess.BlackPawn); got != want {
+		t.Errorf("defended queen loss = %v, want %v", got, want)
+	}
+}
+
+func TestPushesPassedPawnInEndgame(t *testing.T) {
+	newTestAI(t)
+	// 왕이 통과한 폰을 받치고 있고 상대 왕은 멉니다. 폰을 미는 수가 가장 좋습니다.
+	for fen, push := range map[string]string{
+		"8/8/8/8/1PK5/8/8/5k2 w - - 0 1": "b4b5",
+		"8/8/8/1P6/1K6/8/8/7k w - - 0 1": "b5b6",
+		"7k/8/1P6/2K5/8/8/8/8 w - - 0 1": "b6b7",
+	} {
+		pos := mustPos(t, fen)
+		ranked := rankMoves(pos, pos.ValidMoves(), nil, make(transTable), nil)
+		if got := ranked[0].move.String(); got != push {
+			t.Errorf("%s: best move %s, want %s", fen, got, push)
+		}
+	}
+
+	// 더 나아간 통과한 폰일수록 엔드게임 가점이 큽니다.
+	e4 := evaluateSides(mustPos(t, "7k/8/8/8/4P3/8/8/4K3 w - - 0 1"))[chess.White].Endgame
+	e6 := evaluateSides(mustPos(t, "7k/8/4P3/8/8/8/8/4K3 w - - 0 1"))[chess.White].Endgame
+	if e6 <= e4 {
+		t.Errorf("endgame bonus: pawn on e6 %v <= e4 %v", e6, e4)
+	}
+	// 기물이 모두 남은 오프닝에서는 엔드게임 항목이 없습니다.
+	if got := evaluateSides(mustPos(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"))[chess.White].Endgame; got != 0 {
+		t.Errorf("opening endgame term = %v, want 0", got)
+	}
+}
+
+func TestBishopPairAndKnightOutpost(t *testing.T) {
+	newTestAI(t)
+	pair := mustPos(t, "4k3/pppppppp/8/8/8/8/PPPPPPPP/2B1KB2 w - - 0 1")
+	mixed := mustPos(t, "4k3/pppppppp/8/8/8/8/PPPPPPPP/2B1KN2 w - - 0 1")
+	if p, m := evaluateSides(pair)[chess.White].BishopPair, evaluateSides(mixed)[chess.White].BishopPair; p != ai.BishopPairBonus || m != 0 {
+		t.Errorf("bishop pair term = %v (two bishops), %v (bishop+knight), want %v and 0", p, m, ai.BishopPairBonus)
+	}
+	if evaluate(pair) <= evaluate(mixed) {
+		t.Errorf("two bishops %v <= bishop+knight %v", evaluate(pair), evaluate(mixed))
+	}
+	// 폰이 없는 열린 판에서는 비숍 쌍 가점이 1.5배입니다.
+	open := mustPos(t, "4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1")
+	if got := evaluateSides(open)[chess.White].BishopPair; got != 1.5*ai.BishopPairBonus {
+		t.Errorf("open bishop pair = %v, want %v", got, 1.5*ai.BishopPairBonus)
+	}
+
+	// d5 나이트를 e4 폰이 지키고 c·e 파일에 나이트를 쫓아낼 흑 폰이 없습니다.
+	outpost := mustPos(t, "4k3/8/3p4/3N4/4P3/8/8/4K3 w - - 0 1")
+	passive := mustPos(t, "4k3/8/3p4/8/4P3/8/8/1N2K3 w - - 0 1")
+	chased := mustPos(t, "4k3/2p5/3p4/3N4/4P3/8/8/4K3 w - - 0 1") // c7 폰이 c6으로 나와 쫓아낼 수 있음
+	if got := evaluateSides(outpost)[chess.White].Outposts; got != ai.OutpostBonus {
+		t.Errorf("outpost term = %v, want %v", got, ai.OutpostBonus)
+	}
+	if got := evaluateSides(chased)[chess.White].Outposts; got != 0 {
+		t.Errorf("attackable knight outpost term = %v, want 0", got)
+	}
+	if evaluate(outpost) <= evaluate(passive) {
+		t.Errorf("outposted knight %v <= passive knight %v", evaluate(outpost), evaluate(passive))
+	}
+}
+
+func TestRookOnOpenFile(t *testing.T) {
+	newTestAI(t)
+	// 같은 d1 룩을 d파일의 폰만 바꿔 비교합니다.
+	rooks := func(fen string) float64 {
+		return evaluateSides(mustPos(t, fen))[chess.White].Rooks
+	}
+	open := rooks("4k3/ppp3pp/8/8/8/8/PPP3PP/3R3K w - - 0 1")      // d파일에 폰 없음
+	halfOpen := rooks("4k3/pppp2pp/8/8/8/8/PPP3PP/3R3K w - - 0 1") // 흑 폰만 있음
+	closed := rooks("4k3/pppp2pp/8/8/8/8/PPPP2PP/3R3K w - - 0 1")  // 양쪽 폰이 다 있음
+	if open != rookOpenFileBonus || halfOpen != rookHalfOpenFileBonus || closed != 0 {
+		t.Errorf("rook term open %v, half-open %v, closed %v", open, halfOpen, closed)
+	}
+	if !(open > halfOpen && halfOpen > closed) {
+		t.Errorf("want open > half-open > closed, got %v, %v, %v", open, halfOpen, closed)
+	}
+}
+
+func TestCenterControl(t *testing.T) {
+	newTestAI(t)
+	// 같은 기물로 백이 e4·d4 폰과 c3·f3 나이트로 중앙을 잡은 경우와 변두리 수만 둔 경우
+	classical := mustPos(t, "rnbqkbnr/pppppppp/8/8/3PP3/2N2N2/PPP2PPP/R1BQKB1R b KQkq - 0 3")
+	passive := mustPos(t, "rnbqkbnr/pppppppp/8/8/8/N6N/PPPPPPPP/R1BQKB1R b KQkq - 0 3")
+	c, p := evaluateSides(classical)[chess.White].Center, evaluateSides(passive)[chess.White].Center
+	if c <= p {
+		t.Errorf("center term: classical %v <= passive %v", c, p)
+	}
+	if board := classical.Board(); centerControl(board, attackMaps(board), chess.Black) >= c {
+		t.Errorf("black center %v >= white %v in the classical setup", centerControl(board, attackMaps(board), chess.Black), c)
+	}
+	// 중앙 장악은 게임 단계를 곱하므로 기물이 없는 엔드게임에서는 0입니다.
+	if got := evaluateSides(mustPos(t, "4k3/8/8/8/3PP3/8/8/4K3 w - - 0 1"))[chess.White].Center; got != 0 {
+		t.Errorf("endgame center term = %v, want 0", got)
+	}
+}
+
+func TestEvaluateSignConvention(t *testing.T) {
+	newTestAI(t)
+	cases := []struct {
+		fen    string
+		better chess.Color
+	}{
+		{"3qk3/8/8/8/8/8/PPPP4/3QK3 w - - 0 1", chess.White}, // 백이 폰 넷 앞섬, 백 차례
+		{"3qk3/8/8/8/8/8/PPPP4/3QK3 b - - 0 1", chess.White}, // 같은 배치, 흑 차례
+		{"3qk3/pppp4/8/8/8/8/8/3QK3 w - - 0 1", chess.Black}, // 흑이 폰 넷 앞섬, 백 차례
+		{"3qk3/pppp4/8/8/8/8/8/3QK3 b - - 0 1", chess.Black}, // 같은 배치, 흑 차례
+	}
+	for _, c := range cases {
+		pos := mustPos(t, c.fen)
+		// evaluate는 둘 차례인 쪽 관점입니다.
+		score := evaluate(pos)
+		if (pos.Turn() == c.better) != (score > 0) {
+			t.Errorf("%s: evaluate = %v, want it positive only when %s is to move", c.fen, score, c.better.Name())
+		}
+		// evaluateBoard는 차례와 관계없이 주어진 색 관점입니다.
+		if w, b := evaluateBoard(pos, chess.White), evaluateBoard(pos, chess.Black); w != -b || (w > 0) != (c.better == chess.White) {
+			t.Errorf("%s: evaluateBoard white %v black %v", c.fen, w, b)
+		}
+	}
+
+	// 배치가 같으면 둘 차례인 쪽이 템포 가점을 받습니다.
+	w, b := evaluateBoard(mustPos(t, "4k3/8/8/8/8/8/8/4K3 w - - 0 1"), chess.White), evaluateBoard(mustPos(t, "4k3/8/8/8/8/8/8/4K3 b - - 0 1"), chess.White)
+	if w-b != 2*ai.Tempo {
+		t.Errorf("tempo: white to move %v, black to move %v, want a difference of %v", w, b, 2*ai.Tempo)
+	}
+}
+
+// 보드를 위아래로 뒤집고 두 색을 맞바꾼 FEN을 만듭니다. (차례, 캐슬링 권리, 앙파상 칸도 함께 바꿉니다)
+func colorFlipFEN(t *testing.T, fen string) string {
+	t.Helper()
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		t.Fatalf("bad FEN %q", fen)
+	}
+	swapCase := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsUpper(r) {
+				return unicode.ToLower(r)
+			}
+			return unicode.ToUpper(r)
+		}, s)
+	}
+	ranks := strings.Split(fields[0], "/")
+	slices.Reverse(ranks)
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	fields[1] = map[string]string{"w": "b", "b": "w"}[fields[1]]
+	if fields[2] != "-" {
+		fields[2] = swapCase(fields[2])
+	}
+	if ep := fields[3]; ep != "-" {
+		fields[3] = ep[:1] + string('1'+'8'-ep[1])
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestEvaluateColorSymmetry(t *testing.T) {
+	newTestAI(t)
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2",
+		"r1bq1rk1/ppp2ppp/2n2n2/3pp3/1bPP4/2N1PN2/PP1B1PPP/R2QKB1R b KQ - 0 7",
+		"8/5k2/3p4/1p1Pp2p/pP2Pp1P/P4P1K/8/8 b - - 0 1",
+	} {
+		flipped := colorFlipFEN(t, fen)
+		// evaluate는 둘 차례인 쪽 관점이므로, 색을 맞바꾼 포지션도 같은 값이어야 합니다.
+		a, b := evaluate(mustPos(t, fen)), evaluate(mustPos(t, flipped))
+		if math.Abs(a-b) > 1e-9 {
+			t.Errorf("%s: evaluate = %v, color-flipped %s = %v", fen, a, flipped, b)
+		}
+		if w, fb := evaluateBoard(mustPos(t, fen), chess.White), evaluateBoard(mustPos(t, flipped), chess.Black); math.Abs(w-fb) > 1e-9 {
+			t.Errorf("%s: white %v, black after color flip %v", fen, w, fb)
+		}
+	}
+}
+
+func TestFlipTurnIsNullMove(t *testing.T) {
+	newTestAI(t)
+	pos := mustPos(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+	null := flipTurn(pos)
+	if null == nil {
+		t.Fatal("flipTurn returned nil")
+	}
+	if got, want := null.String(), "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2"; got != want {
+		t.Errorf("flipTurn = %q, want %q", got, want)
+	}
+	// 위협·걸린 기물이 없는 포지션에서 차례만 바꾸면 템포 가점만 옮겨 갑니다.
+	if sum := evaluate(pos) + evaluate(null); math.Abs(sum-2*ai.Tempo) > 1e-9 {
+		t.Errorf("evaluate(pos) + evaluate(null) = %v, want %v", sum, 2*ai.Tempo)
+	}
+	// 기동력은 차례와 관계없이 같은 공격 정보에서 나옵니다.
+	if a, b := evaluateSides(pos), evaluateSides(null); a[chess.White].Mobility != b[chess.White].Mobility || a[chess.Black].Mobility != b[chess.Black].Mobility {
+		t.Errorf("mobility changed with the turn: %+v vs %+v", a, b)
+	}
+}
+
+func TestFlipTurnMatchesFENPath(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b Kq - 3 17",
+		"8/5k2/3p4/1p1Pp2p/pP2Pp1P/P4P1K/8/8 b - - 42 60",
+	} {
+		pos := mustPos(t, fen)
+		fast, slow := flipTurn(pos), flipTurnFEN(pos)
+		if fast == nil || slow == nil || fast.String() != slow.String() {
+			t.Errorf("%s: flipTurn %v, FEN path %v", fen, fast, slow)
+		}
+		// 바이너리로 만든 결과를 확인하는 검사가 맞는 포지션은 받고 틀린 포지션은 거릅니다.
+		if !isFlipOf(slow, pos) || isFlipOf(pos, pos) {
+			t.Errorf("%s: isFlipOf accepts the wrong position or rejects the right one", fen)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// Q테이블을 다 읽었는지. 읽기 전에는 /ready가 503을 돌려주고 저장도 하지 않습니다.
+var brainReady atomic.Bool
+
+var errNotReady = errors.New("q-table is still loading")
+
+// GET /health
+// 서버가 떠 있으면 항상 200입니다. 잠금을 잡지 않으므로 Q테이블을 읽는 중에도 바로 답합니다.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// GET /ready
+// Q테이블을 다 읽었으면 200, 아직 읽는 중이면 503입니다. 로드 밸런서가 트래픽을 보내도 되는지 판단합니다.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	// 준비 확인은 자주 오므로 writeError처럼 로그를 남기지 않습니다.
+	if !brainReady.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": errNotReady.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
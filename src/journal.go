@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// 저장 로그(저널)
+// 저장할 때마다 Q테이블 전체를 다시 쓰는 대신, 지난 저장 뒤 바뀐 상태의 행만 한 줄씩(JSON Lines)
+// qtable.json.log 끝에 덧붙입니다. 읽을 때는 스냅숏(qtable.json[.gz])을 읽은 뒤 로그를 순서대로 다시 적용합니다.
+// 로그가 journal_max_mb를 넘거나 한꺼번에 많이 바뀌면(초기화, 가져오기, 정리, 상한 초과 삭제), 그리고 로그를
+// 다시 적용하고 시작한 뒤 처음 저장할 때는 스냅숏을 새로 쓰고 로그를 비웁니다. (압축)
+//
+// 스냅숏과 로그는 세대 번호(journal_gen)로 짝을 맞춥니다. 압축할 때는 세대를 올린 스냅숏을 먼저 쓰고
+// 로그를 새 세대로 다시 시작하므로, 그 사이에 죽어도 옛 로그를 새 스냅숏 위에 잘못 적용하지 않습니다.
+
+// 로그 한 줄. 첫 줄은 세대(gen)만 담고, 나머지는 상태 행 하나, 판 수 등 요약, 학습 현황 한 점 중 하나입니다.
+type journalRecord struct {
+	Gen      *int               `json:"gen,omitempty"`
+	State    string             `json:"s,omitempty"`
+	A        map[string]float64 `json:"a,omitempty"` // 상태 행 전체 (QTable)
+	B        map[string]float64 `json:"b,omitempty"` // QB 행
+	V        map[string]int     `json:"v,omitempty"` // 방문 횟수 행
+	Deleted  bool               `json:"del,omitempty"`
+	Meta     *journalMeta       `json:"meta,omitempty"`
+	Progress *progressPoint     `json:"progress,omitempty"`
+}
+
+type journalMeta struct {
+	GameCount int     `json:"game_count"`
+	Rating    float64 `json:"rating"`
+	Results   string  `json:"results"`
+}
+
+// 로그 한 줄의 최대 길이 (상태 행 하나는 이보다 훨씬 작습니다)
+const journalMaxLine = 16 << 20
+
+func journalPath() string {
+	return qFile + ".log"
+}
+
+// 상태 행이 바뀌었음을 표시합니다. 다음 로그 저장 때 행 전체를 씁니다. ai.mu 쓰기 잠금 아래에서 호출합니다.
+func markDirty(state string) {
+	ai.dirty[state] = struct{}{}
+}
+
+// 한꺼번에 많은 상태가 바뀌어 다음 저장을 스냅숏으로 하게 합니다. ai.mu 쓰기 잠금 아래에서 호출합니다.
+func needSnapshot() {
+	ai.fullSave = true
+}
+
+// 이번 저장을 로그 덧붙이기로 할 수 있는지 봅니다.
+func journalUsable() bool {
+	ai.mu.RLock()
+	ok := ai.Journal && !ai.fullSave
+	limit := int64(ai.JournalMaxMB) << 20
+	ai.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(journalPath())
+	return err == nil && info.Size() < limit || os.IsNotExist(err)
+}
+
+// 바뀐 행을 버퍼에 담고 표시를 지운 뒤 로그에 덧붙입니다. 바뀐 행만 담으므로 쓰기 잠금은 짧습니다.
+// 쓰기에 실패하면 journalFailed로 다음 저장을 스냅숏으로 예약합니다. saveMu 아래에서 호출합니다.
+func appendJournal() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	ai.mu.Lock()
+	for state := range ai.dirty {
+		rec := journalRecord{State: state}
+		if row, ok := ai.QTable[state]; ok {
+			rec.A, rec.B, rec.V = row, ai.QB[state], ai.Visits[state]
+		} else {
+			rec.Deleted = true
+		}
+		enc.Encode(rec)
+	}
+	enc.Encode(journalRecord{Meta: &journalMeta{GameCount: ai.GameCount, Rating: ai.Rating, Results: ai.Results}})
+	for i := range ai.Progress {
+		if p := ai.Progress[i]; p.GameCount > ai.journaledProgress {
+			enc.Encode(journalRecord{Progress: &p})
+			ai.journaledProgress = p.GameCount
+		}
+	}
+	ai.dirty = make(map[string]struct{})
+	gen := ai.JournalGen
+	ai.mu.Unlock()
+
+	if err := writeJournal(gen, buf.Bytes()); err != nil {
+		journalFailed()
+		return err
+	}
+	return nil
+}
+
+// 로그 끝에 덧붙이고 디스크에 내립니다. 로그가 없으면 세대 줄부터 씁니다.
+func writeJournal(gen int, data []byte) error {
+	f, err := os.OpenFile(journalPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		data = append([]byte(`{"gen":`+strconv.Itoa(gen)+"}\n"), data...)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// 스냅숏을 새로 쓴 뒤 로그를 gen 세대로 다시 시작합니다.
+// 저장 로그를 끈 설정이면 로그를 만들지 않고, 전에 쓰던 로그가 남아 있으면 지웁니다.
+func resetJournal(gen int) error {
+	ai.mu.RLock()
+	on := ai.Journal
+	ai.mu.RUnlock()
+	if !on {
+		if err := os.Remove(journalPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(journalPath(), []byte(`{"gen":`+strconv.Itoa(gen)+"}\n"))
+}
+
+// 로그나 스냅숏을 쓰지 못했으면 빠진 변경이 없도록 다음 저장을 스냅숏으로 합니다.
+func journalFailed() {
+	ai.mu.Lock()
+	ai.fullSave = true
+	ai.mu.Unlock()
+}
+
+// 스냅숏을 읽은 뒤 로그를 다시 적용합니다. ai.mu 잠금 아래에서 호출합니다.
+// 적용한 줄 수를 돌려줍니다. 세대가 스냅숏과 다르면 적용하지 않고 오류를 돌려줍니다.
+// 쓰다 만 마지막 줄(저장 도중 종료)은 건너뛰고, 중간 줄이 깨져 있으면 그 앞까지만 적용하고 오류를 돌려줍니다.
+func replayJournal() (int, error) {
+	f, err := os.Open(journalPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), journalMaxLine)
+	if !sc.Scan() {
+		return 0, sc.Err()
+	}
+	var head journalRecord
+	if err := json.Unmarshal(sc.Bytes(), &head); err != nil || head.Gen == nil {
+		return 0, errors.New("journal: missing generation header")
+	}
+	if *head.Gen != ai.JournalGen {
+		return 0, fmt.Errorf("journal: generation %d does not match snapshot %d", *head.Gen, ai.JournalGen)
+	}
+	n := 0
+	var bad error // 읽지 못한 줄. 뒤에 줄이 더 있으면 마지막 줄이 아니므로 오류입니다.
+	for sc.Scan() {
+		if bad != nil {
+			return n, bad
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			bad = fmt.Errorf("journal: line %d: %w", n+2, err)
+			continue
+		}
+		applyJournal(rec)
+		n++
+	}
+	if bad != nil {
+		// 깨진 줄 뒤에 덧붙이지 않도록 다음 저장은 스냅숏으로 하고 로그를 새로 씁니다.
+		slog.Warn("저장 로그의 쓰다 만 마지막 줄을 건너뜁니다", "path", journalPath(), "err", bad)
+		needSnapshot()
+	}
+	return n, sc.Err()
+}
+
+func applyJournal(rec journalRecord) {
+	switch {
+	case rec.Meta != nil:
+		ai.GameCount, ai.Rating, ai.Results = rec.Meta.GameCount, rec.Meta.Rating, rec.Meta.Results
+	case rec.Progress != nil:
+		ai.Progress = append(ai.Progress, *rec.Progress)
+		if n := len(ai.Progress); n > maxProgress {
+			ai.Progress = ai.Progress[n-maxProgress:]
+		}
+	case rec.Deleted:
+		delete(ai.QTable, rec.State)
+		delete(ai.QB, rec.State)
+		delete(ai.Visits, rec.State)
+	case rec.State != "":
+		if rec.A == nil {
+			rec.A = make(map[string]float64)
+		}
+		ai.QTable[rec.State] = rec.A
+		if rec.B != nil {
+			ai.QB[rec.State] = rec.B
+		} else {
+			delete(ai.QB, rec.State)
+		}
+		if rec.V != nil {
+			ai.Visits[rec.State] = rec.V
+		} else {
+			delete(ai.Visits, rec.State)
+		}
+	}
+}
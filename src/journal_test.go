@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Q테이블을 비우고 저장 파일에서 다시 읽습니다.
+func reloadBrain() {
+	ai.QTable, ai.QB, ai.Visits = nil, nil, nil
+	ai.GameCount, ai.JournalGen = 0, 0
+	loadFromFile()
+}
+
+func TestSnapshotPlusJournalMatchesFullSave(t *testing.T) {
+	newTestAI(t)
+	populateQTable(200)
+	needSnapshot()
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 스냅숏 뒤의 변경: 행 수정, 새 행, 삭제, 판 수
+	i := 0
+	for state, row := range ai.QTable {
+		i++
+		switch {
+		case i <= 5:
+			row["changed"] = float64(i)
+			ai.Visits[state] = map[string]int{"changed": i}
+			markDirty(state)
+		case i <= 8:
+			delete(ai.QTable, state)
+			markDirty(state)
+		}
+	}
+	ai.QTable["new"] = map[string]float64{"e2e4": 7}
+	markDirty("new")
+	ai.GameCount = 42
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(journalPath()); err != nil || strings.Count(string(data), "\n") < 10 {
+		t.Fatalf("journal after incremental save: %d lines, err %v", strings.Count(string(data), "\n"), err)
+	}
+	want, wantVisits := ai.QTable, ai.Visits
+
+	reloadBrain()
+	fromJournal, journalVisits := ai.QTable, ai.Visits
+	if !reflect.DeepEqual(fromJournal, want) || !reflect.DeepEqual(journalVisits, wantVisits) || ai.GameCount != 42 {
+		t.Fatalf("snapshot + journal: %d states, game_count %d; want %d states, 42", len(fromJournal), ai.GameCount, len(want))
+	}
+
+	// 같은 테이블을 로그 없이 전체 저장한 것과 비교합니다.
+	qFile = filepath.Join(t.TempDir(), "qtable.json")
+	ai.Journal = false
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	reloadBrain()
+	if !reflect.DeepEqual(ai.QTable, fromJournal) || !reflect.DeepEqual(ai.Visits, journalVisits) || ai.GameCount != 42 {
+		t.Errorf("full save: %d states, game_count %d; snapshot + journal: %d states", len(ai.QTable), ai.GameCount, len(fromJournal))
+	}
+}
+
+func TestReplayJournalSkipsOnlyTornLastLine(t *testing.T) {
+	newTestAI(t)
+	header := `{"gen":` + strconv.Itoa(ai.JournalGen) + "}\n"
+	good := `{"s":"a","a":{"e2e4":1}}` + "\n"
+
+	// 저장 도중 끊긴 마지막 줄은 건너뛰고, 다음 저장을 스냅숏으로 예약합니다.
+	if err := os.WriteFile(journalPath(), []byte(header+good+`{"s":"b","a":{"e2`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := replayJournal()
+	if n != 1 || err != nil || ai.QTable["a"]["e2e4"] != 1 || !ai.fullSave {
+		t.Errorf("torn last line: applied %d, err %v, fullSave %v", n, err, ai.fullSave)
+	}
+
+	// 중간 줄이 깨져 있으면 그 앞까지만 적용하고 오류입니다.
+	if err := os.WriteFile(journalPath(), []byte(header+"not json\n"+good), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := replayJournal(); n != 0 || err == nil {
+		t.Errorf("broken middle line: applied %d, err %v, want an error", n, err)
+	}
+}
+
+func TestSnapshotWritesJournalOnlyWhenEnabled(t *testing.T) {
+	newTestAI(t)
+	ai.QTable["s"] = map[string]float64{"e2e4": 1}
+	if err := os.WriteFile(journalPath(), []byte(`{"gen":0}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ai.Journal = false
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(journalPath()); !os.IsNotExist(err) {
+		t.Errorf("journal off: stale log still exists (err %v)", err)
+	}
+
+	ai.Journal = true
+	needSnapshot()
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(journalPath()); err != nil {
+		t.Errorf("journal on: log not started after snapshot: %v", err)
+	}
+}
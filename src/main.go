@@ -1,159 +1,1646 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
+
+	"chess-ai/stockfish"
 
 	"github.com/notnil/chess"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type ChessAI struct {
-	QTable      map[string]map[string]float64 `json:"q_table"`
-	GameCount   int                           `json:"game_count"`
-	MoveHistory []string                      `json:"-"`
-	mu          sync.RWMutex
+	Config            `json:"-"`                    // 설정은 config.json에서 따로 읽습니다
+	QTable            map[string]map[string]float64 `json:"q_table"`
+	QB                map[string]map[string]float64 `json:"q_table_b,omitempty"` // double_q용 두 번째 Q테이블
+	Visits            map[string]map[string]int     `json:"visits"`              // 상태-수별 학습 횟수
+	GameCount         int                           `json:"game_count"`
+	Rating            float64                       `json:"rating"`                // /move로 둔 대국의 Elo 레이팅
+	Results           string                        `json:"results,omitempty"`     // 최근 대국 결과 (AI 관점 'W'·'D'·'L', 오래된 것부터)
+	Progress          []progressPoint               `json:"progress,omitempty"`    // progress_every판마다 남긴 학습 현황 (/progress)
+	JournalGen        int                           `json:"journal_gen,omitempty"` // 이 스냅숏에 이어 붙일 저장 로그의 세대
+	sessions          map[string]*session           // 세션별 학습 기록과 실제 대국 기록
+	dirty             map[string]struct{}           // 지난 저장 뒤 바뀐 상태 (저장 로그에 씀)
+	fullSave          bool                          // 다음 저장을 로그 대신 스냅숏으로 할지
+	journaledProgress int                           // 저장 로그에 쓴 마지막 학습 현황의 판 수
+	replay            replayBuffer                  // 지난 대국의 전이 (경험 재생용)
+	rng               *rand.Rand                    // 탐색·동점 처리·경험 재생에 쓰는 난수 생성기 (mu 아래에서 사용)
+	mu                sync.RWMutex
+}
+
+var ai = &ChessAI{
+	Config:   defaultConfig(),
+	QTable:   make(map[string]map[string]float64),
+	QB:       make(map[string]map[string]float64),
+	Visits:   make(map[string]map[string]int),
+	Rating:   defaultRating,
+	sessions: make(map[string]*session),
+	dirty:    make(map[string]struct{}),
+	rng:      rand.New(rand.NewSource(loadSeed())),
+}
+
+// Q테이블 파일 경로 (-qfile). gzip 설정이면 끝에 .gz가 붙습니다.
+var qFile = "qtable.json"
+
+const (
+	sessionTTL = 30 * time.Minute // 이 시간 동안 요청이 없는 세션은 정리
+
+	shutdownTimeout = 10 * time.Second // 종료 시 진행 중인 요청을 기다리는 시간
+)
+
+// 기본 난수 시드. CHESS_SEED 환경변수가 없으면 현재 시각을 씁니다.
+// -seed(또는 CHESS_SEED)로 시드를 고정하면 같은 Q테이블에서 시작한 학습이 같은 대국을 둡니다.
+// 셀프 플레이는 작업자끼리 합치는 순서가 달라질 수 있으므로 이때는 작업자를 하나만 씁니다. (fixedSeed)
+func loadSeed() int64 {
+	if v := os.Getenv("CHESS_SEED"); v != "" {
+		if seed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return seed
+		}
+	}
+	return time.Now().UnixNano()
 }
 
-var ai = &ChessAI{QTable: make(map[string]map[string]float64)}
+// -seed나 CHESS_SEED로 시드를 고정했는지. 고정했으면 셀프 플레이를 작업자 하나로 재현되게 둡니다.
+var fixedSeed bool
 
-const qFile = "qtable.json"
+// 설정, 오프닝 북, Q테이블을 읽습니다. -qfile 플래그를 읽은 뒤 main에서 호출합니다.
+func loadAll() {
+	loadSettings()
+	loadBrain()
+}
+
+// 설정과 오프닝 북을 읽습니다.
+func loadSettings() {
+	ai.Config = loadConfig(configFile)
+	openingBook = loadBook(bookFile)
+}
 
-func init() {
-	file, err := os.ReadFile(qFile)
+// Q테이블을 읽고 준비 완료로 표시합니다.
+func loadBrain() {
+	ai.mu.Lock()
+	loadBrainLocked()
+}
+
+// ai.mu를 잡은 채로 불러 Q테이블을 읽고 잠금을 풉니다. 서버는 큰 파일을 읽는 동안에도 /health에
+// 답하도록 잠금을 먼저 잡고 이 함수를 고루틴으로 부릅니다. 그동안 다른 요청은 ai.mu에서 기다립니다.
+func loadBrainLocked() {
+	start := time.Now()
+	loadFromFile()
+	states := len(ai.QTable)
+	ai.mu.Unlock()
+	brainReady.Store(true)
+	slog.Info("Q테이블 읽기 완료", "path", qPath(), "states", states, "duration", time.Since(start))
+}
+
+// Q테이블 파일 경로. 설정에 따라 gzip 압축 파일(.gz)을 씁니다.
+func qPath() string {
+	if ai.Gzip {
+		return qFile + ".gz"
+	}
+	return qFile
+}
+
+// 저장된 Q테이블을 읽습니다. 설정한 형식의 파일이 없으면 다른 형식의 파일을 찾아 읽습니다.
+// 파일이 깨져 있으면 .corrupt로 옮겨 두고 빈 테이블로 시작합니다.
+//
+// 기본 설정(gzip, journal)에서 저장 파일은 qtable.json.gz와 qtable.json.log입니다. 저장소에 들어 있는
+// 평문 qtable.json은 .gz가 아직 없을 때 한 번 읽히고, 첫 저장부터는 .gz로 옮겨 가므로 더는 읽거나 고치지 않습니다.
+// 평문으로 계속 쓰려면 gzip을 끄세요.
+func loadFromFile() {
+	path := qPath()
+	if _, err := os.Stat(path); err != nil {
+		if ai.Gzip {
+			path = qFile
+		} else {
+			path = qFile + ".gz"
+		}
+		if _, err := os.Stat(path); err == nil {
+			slog.Info("설정과 다른 형식의 Q테이블을 읽습니다. 다음 저장부터는 설정한 형식으로 씁니다", "path", path, "save_path", qPath())
+		}
+	}
+	file, err := readQFile(path)
 	if err == nil {
-		json.Unmarshal(file, &ai)
+		err = json.Unmarshal(file, &ai)
 	}
+	if err != nil && !os.IsNotExist(err) {
+		slog.Error("Q테이블 파일이 손상되어 .corrupt로 백업하고 새로 시작합니다", "path", path, "err", err)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			slog.Error("손상된 파일 백업 실패", "path", path, "err", err)
+		}
+		ai.QTable = nil
+		ai.QB = nil
+		ai.Visits = nil
+		ai.GameCount = 0
+		ai.Rating = defaultRating
+		ai.Results = ""
+		ai.Progress = nil
+		ai.JournalGen = 0
+		needSnapshot()
+	}
+	if ai.QTable == nil {
+		ai.QTable = make(map[string]map[string]float64)
+	}
+	if ai.QB == nil {
+		ai.QB = make(map[string]map[string]float64)
+	}
+	if ai.Visits == nil {
+		ai.Visits = make(map[string]map[string]int)
+	}
+	// 스냅숏 뒤에 저장 로그를 이어 붙입니다. 적용했으면 다음 저장에서 스냅숏으로 합칩니다.
+	n, err := replayJournal()
+	if err != nil {
+		slog.Warn("저장 로그를 끝까지 적용하지 못했습니다", "path", journalPath(), "applied", n, "err", err)
+	}
+	if n > 0 || err != nil {
+		needSnapshot()
+	}
+	if len(ai.Progress) > 0 {
+		ai.journaledProgress = ai.Progress[len(ai.Progress)-1].GameCount
+	}
+	migrateStateKeys()
+	evictStates()
 }
 
-// [핵심] 기물별 가치를 정의합니다.
-func getPieceValue(p chess.Piece) float64 {
-	values := map[chess.PieceType]float64{
-		chess.Pawn:   10.0,
-		chess.Knight: 30.0,
-		chess.Bishop: 30.0,
-		chess.Rook:   50.0,
-		chess.Queen:  90.0,
-		chess.King:   900.0, // 왕은 절대적 가치
+// Q테이블 상태 키: 정규화한 FEN입니다.
+func stateKey(pos *chess.Position) string {
+	return normalizeFEN(pos.String())
+}
+
+// Q테이블 상태 키로 쓸 FEN에서 반수/전체 수 카운터를 떼어냅니다.
+// 같은 배치라면 몇 수째에 도달했든 같은 상태로 취급하기 위함입니다.
+func normalizeFEN(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return strings.TrimSpace(fen)
 	}
-	val := values[p.Type()]
-	return val
+	return strings.Join(fields[:4], " ")
 }
 
-// 보드 상태의 점수를 계산합니다.
-func evaluateBoard(pos *chess.Position) float64 {
-	score := 0.0
-	board := pos.Board()
-	for i := 0; i < 64; i++ {
-		p := board.Piece(chess.Square(i))
-		if p != chess.NoPiece {
-			val := getPieceValue(p)
-			if p.Color() == chess.Black { // AI 색상
-				score += val
-			} else {
-				score -= val
+// 예전 형식(전체 FEN 키)으로 저장된 Q테이블을 정규화된 키로 합칩니다.
+// 같은 키로 모이는 값은 평균을 내고, 방문 횟수는 더합니다.
+func migrateStateKeys() {
+	ai.QTable = mergeStateKeys(ai.QTable)
+	ai.QB = mergeStateKeys(ai.QB)
+
+	visits := make(map[string]map[string]int, len(ai.Visits))
+	for fen, actions := range ai.Visits {
+		key := normalizeFEN(fen)
+		if visits[key] == nil {
+			visits[key] = make(map[string]int)
+		}
+		for move, n := range actions {
+			visits[key][move] += n
+		}
+	}
+	ai.Visits = visits
+}
+
+// 한 Q테이블의 키를 정규화된 키로 합칩니다.
+func mergeStateKeys(table map[string]map[string]float64) map[string]map[string]float64 {
+	merged := make(map[string]map[string]float64, len(table))
+	counts := make(map[string]map[string]int)
+	for fen, actions := range table {
+		key := normalizeFEN(fen)
+		if merged[key] == nil {
+			merged[key] = make(map[string]float64)
+			counts[key] = make(map[string]int)
+		}
+		for move, q := range actions {
+			n := counts[key][move]
+			merged[key][move] = (merged[key][move]*float64(n) + q) / float64(n+1)
+			counts[key][move] = n + 1
+		}
+	}
+	return merged
+}
+
+type scoredMove struct {
+	move  *chess.Move
+	score float64 // 최종 점수 (blend(q, eval), 반복·무승부 조정 포함)
+	q     float64 // Q테이블 몫
+	eval  float64 // 탐색 평가 몫
+}
+
+// 수 선택 점수: Q값과 탐색 평가를 q_weight, eval_weight로 섞습니다.
+// 학습된 보상(승패 ±win_reward)과 기물 점수(폰=10)는 크기가 달라서, 학습 초기에는 평가 쪽,
+// 충분히 학습한 뒤에는 Q값 쪽 비중을 키워 조절합니다. Q값은 normalizeQ로 평가 척도에 맞춘 뒤 섞습니다.
+func blend(q, eval float64) float64 {
+	return ai.QWeight*normalizeQ(q) + ai.EvalWeight*eval
+}
+
+// Q값을 평가 척도로 바꿉니다: q_scale * tanh(q / 보상 크기).
+// Q 갱신은 학습률로 목표값 쪽으로 조금씩 옮기는 방식이라 값이 끝없이 쌓이지는 않지만, n단계 보상이나
+// 할인 합 때문에 종료 보상의 몇 배까지 커질 수 있습니다. 그래서 저장된 값은 그대로 두고 고를 때만
+// 종료 보상 크기(win_reward, loss_reward 중 큰 쪽)로 나눠 ±q_scale 안으로 눌러 넣습니다.
+// 승리 수준의 Q값이 q_scale의 약 76%가 되고, 몇 번을 이겨도 q_scale을 넘지 않습니다.
+// q_scale이 0이면 원래 Q값을 그대로 씁니다.
+func normalizeQ(q float64) float64 {
+	if ai.QScale <= 0 {
+		return q
+	}
+	bound := math.Max(math.Abs(ai.WinReward), math.Abs(ai.LossReward))
+	if bound == 0 {
+		return q
+	}
+	return ai.QScale * math.Tanh(q/bound)
+}
+
+// [학습 로직] QTable 점수 + 알파-베타 탐색 점수를 합산해 수를 높은 순으로 정렬합니다.
+// 각 수의 점수는 정렬 전에 한 번만 계산합니다.
+// qrow는 현재 상태의 수별 Q값입니다. stats가 있으면 탐색한 노드 수를 더합니다.
+func rankMoves(pos *chess.Position, moves []*chess.Move, qrow map[string]float64, tt transTable, stats *searchStats) []scoredMove {
+	clear(tt)
+	ranked, _ := rankAtDepth(pos, moves, qrow, tt, ai.SearchDepth, time.Time{}, stats)
+	return ranked
+}
+
+// 반복 심화의 최대 깊이
+const maxIterDepth = 32
+
+// [탐색] 반복 심화: 깊이 1부터 한 단계씩 늘려 가며 budget 안에 끝난 마지막 깊이로 수를 정렬합니다.
+// 앞 깊이에서 좋았던 수부터 다음 깊이를 탐색해 가지치기가 잘 되게 하고, 치환표는 깊이끼리 함께 씁니다.
+// 깊이 1은 시간이 지나도 끝까지 봅니다. 다다른 깊이를 함께 돌려줍니다.
+func rankMovesTimed(pos *chess.Position, moves []*chess.Move, qrow map[string]float64, tt transTable, budget time.Duration, stats *searchStats) ([]scoredMove, int) {
+	clear(tt)
+	deadline := time.Now().Add(budget)
+	ranked, _ := rankAtDepth(pos, moves, qrow, tt, 1, time.Time{}, stats)
+	depth := 1
+	for d := 2; d <= maxIterDepth && time.Now().Before(deadline); d++ {
+		order := make([]*chess.Move, len(ranked))
+		for i, s := range ranked {
+			order[i] = s.move
+		}
+		next, ok := rankAtDepth(pos, order, qrow, tt, d, deadline, stats)
+		if !ok {
+			break
+		}
+		ranked, depth = next, d
+		// 메이트를 찾았으면 더 깊이 볼 필요가 없습니다.
+		if isMateScore(ranked[0].eval) {
+			break
+		}
+	}
+	return ranked, depth
+}
+
+// 각 수를 둔 뒤 depth-1 깊이로 탐색해 점수를 매기고 정렬합니다. deadline을 넘기면 ok가 false입니다.
+func rankAtDepth(pos *chess.Position, moves []*chess.Move, qrow map[string]float64, tt transTable, depth int, deadline time.Time, stats *searchStats) ([]scoredMove, bool) {
+	inf := math.Inf(1)
+	ranked := make([]scoredMove, len(moves))
+	for i, m := range moves {
+		// 수를 둔 뒤에는 상대 차례에서 탐색하므로 부호를 뒤집습니다.
+		q := qrow[m.String()]
+		score, ok := tt.searchUntil(pos.Update(m), depth-1, 1, -inf, inf, deadline, stats)
+		if !ok {
+			return nil, false
+		}
+		eval := -score
+		ranked[i] = scoredMove{move: m, score: blend(q, eval), q: q, eval: eval}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	return ranked, true
+}
+
+// 수를 둘 수 없을 때 후보에 매기는 점수. 어떤 메이트 점수보다도 낮습니다.
+const illegalScore = -2 * mateScore
+
+// game에 m을 둡니다. 둘 수 없으면(대국 기록과 포지션이 어긋난 경우 등) 원인을 찾을 수 있게
+// 기록을 남기고 false를 돌려줍니다. 이때 game은 바뀌지 않습니다.
+func playMove(game *chess.Game, m *chess.Move) bool {
+	if err := game.Move(m); err != nil {
+		slog.Warn("둘 수 없는 수를 건너뜁니다", "fen", game.Position().String(), "move", m.String(), "err", err)
+		return false
+	}
+	return true
+}
+
+// 두면 대국 기록상 무승부(반복, 기물 부족 등)가 되는 수는 탐색 점수 대신 무승부 점수로 다시 매기고 다시 정렬합니다.
+// 반복은 대국 기록이 있어야 알 수 있으므로 최상위 수에서만 확인합니다.
+// 대국 기록에서 둘 수 없는 수는 망가진 포지션을 평가하지 않도록 illegalScore로 맨 뒤에 보냅니다.
+// game이 세션의 대국 기록이면 ai.mu 잠금 아래에서 호출합니다. (복사본도 Position은 함께 씁니다)
+func applyContempt(pos *chess.Position, ranked []scoredMove, qrow map[string]float64, game *chess.Game) {
+	defer sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	for i := range ranked {
+		g := game.Clone()
+		if !playMove(g, ranked[i].move) {
+			ranked[i].eval, ranked[i].score = illegalScore, illegalScore
+			continue
+		}
+		drawn := g.Outcome() == chess.Draw
+		for _, m := range g.EligibleDraws() {
+			if m == chess.ThreefoldRepetition {
+				drawn = true
+			}
+		}
+		if drawn {
+			ranked[i].eval = -drawScore(pos.Update(ranked[i].move))
+			ranked[i].score = blend(ranked[i].q, ranked[i].eval)
+		}
+	}
+}
+
+// 지고 있지 않을 때 이번 대국에서 이미 나온 포지션으로 돌아가는 수를 감점하고 다시 정렬합니다.
+// 앞서 있으면서 같은 수를 왔다 갔다 하다 3회 반복 무승부로 끝나는 것을 막습니다.
+// 지고 있을 때는 무승부가 이득이므로 그대로 둡니다.
+func avoidRepetition(pos *chess.Position, ranked []scoredMove, history []*chess.Position) {
+	if ai.RepetitionPenalty == 0 || evaluate(pos) < 0 {
+		return
+	}
+	seen := make(map[string]bool, len(history))
+	for _, p := range history {
+		seen[normalizeFEN(p.String())] = true
+	}
+	for i := range ranked {
+		if seen[normalizeFEN(pos.Update(ranked[i].move).String())] {
+			ranked[i].score -= ai.RepetitionPenalty
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+}
+
+// 한 상태의 수별 Q값 중 가장 높은 값을 반환합니다. (학습된 수가 없으면 0)
+func maxQ(actions map[string]float64) float64 {
+	if len(actions) == 0 {
+		return 0
+	}
+	best := math.Inf(-1)
+	for _, v := range actions {
+		if v > best {
+			best = v
+		}
+	}
+	return best
+}
+
+// [학습] Q(s,a) += alpha * (r_k + gamma * maxQ(s') - Q(s,a))
+// 기록을 뒤에서부터 갱신해 종료 보상이 앞쪽 수로 전파되게 합니다.
+// 끝에서 k번째 수가 받는 보상은 r_k = reward * decay^k 이므로
+// 종료에 가까운 수일수록 더 큰 몫의 보상(또는 책임)을 받습니다.
+func learn(history []string, reward float64) {
+	alpha := ai.effectiveAlpha(ai.GameCount)
+	replayExperience(learnInto(sharedTables(), ai.Visits, history, reward, alpha, ai.rng))
+	evictStates()
+}
+
+// 상태-수를 n번 학습한 뒤의 학습률입니다. alpha는 판 수로 줄인 값(effectiveAlpha)입니다.
+// adaptive_alpha가 켜져 있으면 1/(1+n)으로 줄어 자주 본 항목은 안정되고 드문 항목은 빨리 배웁니다.
+// 이때 alpha는 학습률의 하한이 되어 나중에 바뀐 결과도 계속 반영합니다.
+func learningRate(n int, alpha float64) float64 {
+	if !ai.AdaptiveAlpha {
+		return alpha
+	}
+	return math.Max(1/float64(1+n), alpha)
+}
+
+// learn과 같지만 주어진 Q테이블과 방문 횟수 표를 갱신합니다.
+// 학습에 쓴 전이들을 경험 재생 버퍼에 넣을 수 있게 돌려줍니다.
+// lambda가 0보다 크면 n_step 대신 적격 흔적(Q(λ))으로 갱신합니다.
+func learnInto(tables qTables, visits map[string]map[string]int, history []string, reward, alpha float64, r *rand.Rand) []transition {
+	if ai.Lambda > 0 {
+		steps := nStepTransitions(history, reward, 1, ai.Gamma)
+		learnTraces(tables, visits, steps, alpha, r)
+		return steps
+	}
+	var transitions []transition
+	steps := nStepTransitions(history, reward, ai.NStep, ai.Gamma)
+	// 뒤에서부터 갱신해 종료 보상이 한 판 안에서 앞쪽 수까지 전해지게 합니다.
+	for i := len(steps) - 1; i >= 0; i-- {
+		t := steps[i]
+		state, move := t.state, t.move
+		if visits[state] == nil {
+			visits[state] = make(map[string]int)
+		}
+		updateQ(tables, t, learningRate(visits[state][move], alpha), r)
+		visits[state][move]++
+		transitions = append(transitions, t)
+		// 좌우로 뒤집은 포지션도 같은 값을 배웁니다. (경험 재생 버퍼에는 넣지 않음)
+		if m, ok := mirrorTransition(t); ok && ai.Augment {
+			if visits[m.state] == nil {
+				visits[m.state] = make(map[string]int)
+			}
+			updateQ(tables, m, learningRate(visits[m.state][m.move], alpha), r)
+			visits[m.state][m.move]++
+		}
+	}
+	return transitions
+}
+
+// 한 판의 기록을 앞에서부터 n단계 전이로 바꿉니다.
+// 수마다 받는 보상 r_i는 중간 보상(기물 득실)에 종료 보상을 끝에서부터 Decay만큼 줄여 더한 값이고,
+// i번째 전이의 보상은 r_i + γr_{i+1} + … + γ^(n-1)r_{i+n-1}, 부트스트랩할 상태는 n수 뒤의 상태입니다.
+// 판이 n수 안에 끝나면 남은 보상만 더하고 부트스트랩하지 않습니다. n이 1 이하면 한 단계 갱신입니다.
+func nStepTransitions(history []string, reward float64, n int, gamma float64) []transition {
+	type record struct {
+		state, move, next string
+		reward            float64
+	}
+	var records []record
+	for _, h := range history {
+		if state, move, shaped, next, ok := parseRecord(h); ok {
+			records = append(records, record{state, move, next, shaped})
+		}
+	}
+	credit := reward
+	for i := len(records) - 1; i >= 0; i-- {
+		records[i].reward += credit
+		credit *= ai.Decay
+	}
+
+	n = max(n, 1)
+	transitions := make([]transition, len(records))
+	for i, rec := range records {
+		t := transition{state: rec.state, move: rec.move, steps: n}
+		discount := 1.0
+		end := min(i+n, len(records))
+		for k := i; k < end; k++ {
+			t.reward += discount * records[k].reward
+			discount *= gamma
+		}
+		// 마지막 수는 종료 보상만, 나머지는 n수 뒤 상태의 가치로 부트스트랩합니다.
+		// 그 상태에서 실제로 고른 수는 SARSA에 씁니다.
+		if end < len(records) {
+			t.next = records[end-1].next
+			t.nextMove = records[end].move
+		}
+		transitions[i] = t
+	}
+	return transitions
+}
+
+// 전이 하나로 Q(s,a)를 한 번 갱신합니다. 다음 상태가 없으면 보상만 목표로 삼습니다.
+// double_q면 두 표 중 하나를 골라 갱신합니다. (qTables.pick)
+// learning_algo가 sarsa면 다음 상태의 최댓값 대신 실제로 둔 다음 수의 값을 씁니다.
+func updateQ(tables qTables, t transition, alpha float64, r *rand.Rand) {
+	table, eval := tables.pick(r)
+	// 상태 목록은 a(QTable)가 기준이므로 b만 갱신해도 a에 행을 만들어 둡니다.
+	ensureRow(tables, table, t.state)
+	target := t.reward
+	if t.next != "" {
+		target += math.Pow(ai.Gamma, float64(max(t.steps, 1))) * eval[t.next][nextAction(table, t)]
+	}
+	old := table[t.state][t.move]
+	table[t.state][t.move] = old + alpha*(target-old)
+}
+
+// 목표값에 쓸 다음 상태의 수. Q-learning은 table에서 가장 높은 수(off-policy),
+// SARSA는 실제로 둔 수(on-policy)입니다. 둔 수를 모르는 전이는 Q-learning처럼 갱신합니다.
+func nextAction(table map[string]map[string]float64, t transition) string {
+	if ai.LearningAlgo == algoSARSA && t.nextMove != "" {
+		return t.nextMove
+	}
+	return argmaxQ(table[t.next])
+}
+
+// 50수 규칙으로 무승부를 청구할 수 있으면 무승부로 끝내고 true를 돌려줍니다.
+// 라이브러리는 75수 규칙만 자동으로 끝내므로 서버가 대신 청구합니다.
+func claimFiftyMove(game *chess.Game) bool {
+	if game.Outcome() != chess.NoOutcome {
+		return false
+	}
+	for _, m := range game.EligibleDraws() {
+		if m == chess.FiftyMoveRule {
+			return game.Draw(m) == nil
+		}
+	}
+	return false
+}
+
+// 클라이언트가 보낸 최종 FEN으로 서버에서도 결과(무승부 종류 등)를 다시 판정합니다.
+// 기물 부족(K 대 K, K+B 대 K 등)과 50수 규칙은 클라이언트가 놓치기 쉬워 FEN으로 무승부 처리합니다.
+// FEN으로 판이 끝나지 않았으면 보낸 결과를 그대로 씁니다. FEN을 읽지 못하면 game이 nil입니다.
+func judgeResult(result, fen string) (string, *chess.Game) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return result, nil
+	}
+	game := chess.NewGame(opt)
+	claimFiftyMove(game)
+	switch game.Outcome() {
+	case chess.BlackWon:
+		result = "Black"
+	case chess.WhiteWon:
+		result = "White"
+	case chess.Draw:
+		result = "Draw"
+	}
+	return result, game
+}
+
+// 게임 결과에 따른 종료 보상을 계산합니다.
+func terminalReward(result, fen string, aiColor chess.Color) (float64, chess.Method) {
+	method := chess.NoMethod
+	result, game := judgeResult(result, fen)
+	if game != nil {
+		method = game.Method()
+		// 이기고 있는데 스테일메이트로 비긴 경우는 약간 감점
+		// (Contempt는 탐색 점수에만 쓰이므로 학습 보상은 여기서 따로 정합니다)
+		if result == "Draw" && method == chess.Stalemate && evaluateBoard(game.Position(), aiColor) > 0 {
+			return ai.StaleReward, method
+		}
+	}
+
+	switch result {
+	case aiColor.Name():
+		return ai.WinReward, method
+	case "Draw":
+		return ai.DrawReward, method
+	default:
+		return ai.LossReward, method
+	}
+}
+
+// 오래 쓰이지 않은 세션 기록을 주기적으로 지웁니다.
+func cleanupSessions(interval time.Duration) {
+	for range time.Tick(interval) {
+		ai.mu.Lock()
+		for id, s := range ai.sessions {
+			if time.Since(s.lastSeen) > sessionTTL {
+				dropSession(id)
 			}
 		}
+		ai.mu.Unlock()
+	}
+}
+
+// 세션의 기록을 모두 지웁니다. ai.mu 잠금 아래에서 호출합니다.
+func dropSession(id string) {
+	delete(ai.sessions, id)
+}
+
+// FEN을 검사해 게임을 만듭니다. 라이브러리의 오류 메시지를 그대로 돌려줍니다.
+// 라이브러리가 확인하지 않는 왕의 개수도 검사합니다.
+func parseFEN(fen string) (*chess.Game, error) {
+	if strings.TrimSpace(fen) == "" {
+		return nil, errors.New("empty FEN")
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	game := chess.NewGame(opt)
+	kings := map[chess.Color]int{}
+	board := game.Position().Board()
+	for i := 0; i < 64; i++ {
+		if p := board.Piece(chess.Square(i)); p.Type() == chess.King {
+			kings[p.Color()]++
+		}
 	}
-	return score
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return nil, errors.New("each side must have exactly one king")
+	}
+	return game, nil
 }
 
-func saveToFile() error {
+// 요청의 color 값("white"/"black")을 해석합니다. 기본값은 흑입니다.
+func parseColor(s string) chess.Color {
+	if strings.EqualFold(s, "white") {
+		return chess.White
+	}
+	return chess.Black
+}
+
+// 저장이 동시에 두 번 돌지 않도록 막습니다.
+var saveMu sync.Mutex
+
+// Q테이블을 저장합니다. journal이 켜져 있으면 보통은 바뀐 행만 저장 로그에 덧붙이고,
+// 가끔 전체 스냅숏을 새로 씁니다. (journal.go)
+func saveToFile() (err error) {
+	start := time.Now()
+	mode := "snapshot"
+	defer func() {
+		recordSave(err)
+		if err != nil {
+			slog.Error("Q테이블 저장 실패", "path", qPath(), "mode", mode, "err", err, "duration", time.Since(start))
+			return
+		}
+		slog.Info("Q테이블 저장", "path", qPath(), "mode", mode, "duration", time.Since(start))
+	}()
+	// 다 읽기 전에 저장하면 빈 테이블로 파일을 덮어씁니다.
+	if !brainReady.Load() {
+		return errNotReady
+	}
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	if journalUsable() {
+		mode = "journal"
+		return appendJournal()
+	}
+	if err := saveSnapshot(); err != nil {
+		journalFailed()
+		return err
+	}
+	return nil
+}
+
+// 전체 스냅숏을 씁니다. 같은 폴더의 임시 파일에 다 쓴 뒤 이름을 바꾸므로
+// 쓰는 도중에 프로세스가 죽어도 기존 파일은 그대로 남습니다. 다 쓰면 저장 로그를 새 세대로 비웁니다.
+// saveMu 아래에서 호출합니다. 세대를 올리고 바뀐 상태 표시를 지운 뒤 내용을 읽으므로,
+// 그 사이의 학습은 스냅숏과 다음 로그에 모두 들어갈 뿐 빠지지 않습니다.
+func saveSnapshot() error {
+	ai.mu.Lock()
+	ai.JournalGen++
+	gen := ai.JournalGen
+	ai.dirty = make(map[string]struct{})
+	ai.fullSave = false
+	if len(ai.Progress) > 0 {
+		ai.journaledProgress = ai.Progress[len(ai.Progress)-1].GameCount
+	}
+	ai.mu.Unlock()
+	ai.mu.RLock()
+	data, err := json.MarshalIndent(ai, "", "  ")
+	ai.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	path := qPath()
+	if ai.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	return resetJournal(gen)
+}
+
+// 주기적으로 Q테이블을 저장합니다.
+func autosave(interval time.Duration) {
+	for range time.Tick(interval) {
+		saveToFile() // 결과는 saveToFile에서 로그로 남깁니다
+	}
+}
+
+// SIGINT/SIGTERM을 받으면 진행 중인 요청을 shutdownTimeout까지 기다린 뒤
+// 마지막으로 저장하고 서버를 닫습니다. 웹소켓 연결은 Shutdown이 부르는 closeWebSockets가 닫습니다.
+func shutdownOnSignal(srv *http.Server, done chan<- struct{}) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	slog.Info("서버 종료 중")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("요청 정리 시간 초과", "err", err)
+	}
+	saveToFile()
+	close(done)
+}
+
+// Q테이블 파일을 읽습니다. .gz 파일이면 압축을 풉니다.
+func readQFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if !strings.HasSuffix(path, ".gz") {
+		return io.ReadAll(file)
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// path에 data를 원자적으로 씁니다. 같은 폴더의 임시 파일에 다 쓴 뒤 이름을 바꿉니다.
+func writeFileAtomic(path string, data []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// write로 임시 파일을 채우고, 끝까지 성공했을 때만 path로 이름을 바꿉니다.
+// 쓰다가 실패하면 임시 파일을 지우므로 기존 파일은 그대로 남습니다.
+func writeAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 이름을 바꾼 뒤에는 아무 일도 하지 않습니다
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// 응답을 JSON으로 씁니다.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// 오류를 {"error": msg} 형태의 JSON으로 씁니다.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody(status, msg))
+}
+
+// 실패를 로그로 남기고 {"error": msg} 응답 본문을 만듭니다.
+func errorBody(status int, msg string) map[string]string {
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(context.Background(), level, "요청 실패", "status", status, "error", msg)
+	return map[string]string{"error": msg}
+}
+
+// 다른 출처(origin)에서 띄운 프론트엔드도 API를 부를 수 있게 CORS 헤더를 붙입니다.
+// 사전 요청(OPTIONS)은 핸들러까지 가지 않고 여기서 바로 응답합니다. cors_origin이 비어 있으면 끕니다.
+func withCORS(origin string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// /move 요청 본문. /ws에서도 같은 형식의 메시지를 받습니다.
+type moveRequest struct {
+	FEN       string  `json:"fen"` // 비우면 세션(POST /newgame)의 현재 포지션
+	Result    string  `json:"result"`
+	SessionID string  `json:"session_id"`
+	Color     string  `json:"color"`           // AI가 두는 색
+	MultiPV   int     `json:"multipv"`         // 응답에 담을 상위 후보 수 (0이면 생략)
+	Opponent  float64 `json:"opponent_rating"` // 결과를 보낼 때 상대의 Elo 레이팅 (0이면 기본값)
+	TimeMs    int     `json:"time_ms"`         // 탐색 시간 제한 (0이면 move_time_ms 설정)
+	Learn     *bool   `json:"learn"`           // false면 이 수(또는 결과)를 학습에 쓰지 않음 (생략하면 learning_enabled 설정)
+}
+
+// 요청의 learn 값이 있으면 그 값을, 없으면 learning_enabled 설정을 돌려줍니다. ai.mu를 잡지 않은 채로 호출합니다.
+func learningEnabled(override *bool) bool {
+	if override != nil {
+		return *override
+	}
 	ai.mu.RLock()
 	defer ai.mu.RUnlock()
-	data, _ := json.MarshalIndent(ai, "", "  ")
-	return os.WriteFile(qFile, data, 0644)
+	return ai.LearningEnabled
 }
 
 func moveHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		FEN    string `json:"fen"`
-		Result string `json:"result"`
-	}
+	var req moveRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
+	status, resp := handleMove(req)
+	writeJSON(w, status, resp)
+}
+
+// /move와 /ws가 함께 쓰는 본문 처리. 응답 상태 코드와 JSON으로 보낼 값을 돌려줍니다.
+// result가 있으면 게임을 마무리해 학습하고, 없으면 fen에서 AI의 수를 고릅니다.
+func handleMove(req moveRequest) (int, interface{}) {
+	aiColor := parseColor(req.Color)
 
 	// 게임 종료 처리
 	if req.Result != "" {
-		ai.mu.Lock()
-		ai.GameCount++
-		reward := -500.0 // 패배 시 기본 감점 강화
-		if req.Result == "Black" {
-			reward = 500.0
-		}
-
-		for _, record := range ai.MoveHistory {
-			parts := strings.Split(record, "|")
-			if len(parts) == 2 {
-				state, move := parts[0], parts[1]
-				if ai.QTable[state] == nil {
-					ai.QTable[state] = make(map[string]float64)
-				}
-				ai.QTable[state][move] += reward
+		opponent := req.Opponent
+		if opponent <= 0 {
+			opponent = defaultRating
+		}
+		method, pgns := finishGame(req.Result, req.FEN, map[string]chess.Color{req.SessionID: aiColor}, opponent, learningEnabled(req.Learn))
+		for _, pgn := range pgns {
+			if err := appendPGN(pgn); err != nil {
+				slog.Error("PGN 저장 실패", "path", pgnFile, "err", err)
 			}
 		}
-		ai.MoveHistory = []string{}
-		ai.mu.Unlock()
+		gamesCompleted.Inc()
 		saveToFile()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "saved"})
-		return
+		ai.mu.RLock()
+		rating := ai.Rating
+		ai.mu.RUnlock()
+		return http.StatusOK, map[string]interface{}{
+			"status": "saved",
+			"method": method.String(),
+			"rating": rating,
+		}
 	}
 
-	fen, _ := chess.FEN(req.FEN)
-	game := chess.NewGame(fen)
-	moves := game.ValidMoves()
+	fillFromSession(&req)
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		return http.StatusBadRequest, errorBody(http.StatusBadRequest, "invalid FEN: "+err.Error())
+	}
+	// 이미 끝난 판(기물 부족, 50수 규칙 등)이면 수를 두지 않고 알려 줍니다.
+	claimFiftyMove(game)
+	if game.Outcome() != chess.NoOutcome {
+		return http.StatusOK, map[string]string{
+			"status":  "game_over",
+			"outcome": game.Outcome().String(),
+			"method":  game.Method().String(),
+		}
+	}
+	moveRequests.Inc()
+	start := time.Now()
+	ai.mu.RLock()
+	budget := time.Duration(ai.MoveTimeMs) * time.Millisecond
+	ai.mu.RUnlock()
+	if req.TimeMs > 0 {
+		budget = time.Duration(req.TimeMs) * time.Millisecond
+	}
+	choice := chooseMove(req.SessionID, game.Position(), budget, learningEnabled(req.Learn))
+	elapsed := time.Since(start)
+	moveLatency.Observe(elapsed.Seconds())
+	if choice.move != nil {
+		slog.Info("수 선택", "session", req.SessionID, "fen", req.FEN, "move", choice.move.String(),
+			"score", choice.score, "depth", choice.depth, "nodes", choice.nodes, "source", choice.source,
+			"explored", choice.explored, "duration", elapsed)
+	}
+	selected := choice.move
+	if selected == nil {
+		// 둘 수 있는 수가 없으면 게임이 끝난 것입니다.
+		return http.StatusOK, map[string]string{
+			"status":  "game_over",
+			"outcome": game.Outcome().String(),
+			"method":  game.Method().String(),
+		}
+	}
+
+	ai.mu.RLock()
+	gameCount, brainSize, epsilon := ai.GameCount, len(ai.QTable), ai.effectiveEpsilon(ai.GameCount)
+	ai.mu.RUnlock()
+
+	// AI의 수를 둔 뒤 게임이 끝났는지 바로 알려 줍니다.
+	after := game.Clone()
+	playMove(after, selected)
+	claimFiftyMove(after)
+	resp := map[string]interface{}{
+		"move":         selected.String(),
+		"uci":          selected.String(),
+		"san":          chess.AlgebraicNotation{}.Encode(game.Position(), selected),
+		"game_count":   gameCount,
+		"brain_size":   brainSize,
+		"epsilon":      epsilon,
+		"explored":     choice.explored,
+		"source":       choice.source,
+		"depth":        choice.depth,
+		"confidence":   choice.conf,
+		"visits":       choice.visits,
+		"outcome":      after.Outcome().String(),
+		"is_game_over": after.Outcome() != chess.NoOutcome,
+		// 탐색 정보 (북의 수면 노드 0, 깊이 0)
+		"nodes_searched":      choice.nodes,
+		"depth_reached":       choice.depth,
+		"time_ms":             elapsed.Milliseconds(),
+		"principal_variation": uciLine(choice.pv),
+	}
+	if after.Outcome() != chess.NoOutcome {
+		resp["method"] = after.Method().String()
+	}
+	if req.MultiPV > 0 {
+		resp["pv"] = principalMoves(game.Position(), choice.ranked, req.MultiPV)
+	}
+	return http.StatusOK, resp
+}
+
+// 주 변화를 UCI 표기 목록으로 바꿉니다.
+func uciLine(pv []*chess.Move) []string {
+	line := make([]string, len(pv))
+	for i, m := range pv {
+		line[i] = m.String()
+	}
+	return line
+}
+
+// 후보 수 하나의 점수 내역 (multipv 응답용)
+type pvMove struct {
+	UCI   string  `json:"uci"`
+	SAN   string  `json:"san"`
+	Score float64 `json:"score"`
+	Q     float64 `json:"q"`
+	Eval  float64 `json:"eval"`
+}
+
+// 선택할 때 매긴 점수로 상위 n개 후보를 돌려줍니다. 오프닝 북으로 고른 경우에는 비어 있습니다.
+func principalMoves(pos *chess.Position, ranked []scoredMove, n int) []pvMove {
+	pv := []pvMove{}
+	for _, s := range ranked {
+		if len(pv) == n {
+			break
+		}
+		pv = append(pv, pvMove{
+			UCI:   s.move.String(),
+			SAN:   chess.AlgebraicNotation{}.Encode(pos, s.move),
+			Score: s.score,
+			Q:     s.q,
+			Eval:  s.eval,
+		})
+	}
+	return pv
+}
+
+// chooseMove의 결과
+type moveChoice struct {
+	move     *chess.Move
+	explored bool          // 최고 점수가 아닌 수를 탐색으로 골랐는지
+	source   string        // "book" 또는 "search"
+	score    float64       // 고른 수의 점수 (북이면 0)
+	depth    int           // 탐색 깊이 (북이면 0)
+	ranked   []scoredMove  // 탐색으로 고른 경우 점수순 후보 (기록하지 않음)
+	nodes    int           // 탐색한 노드 수 (북이면 0)
+	pv       []*chess.Move // 고른 수부터 이어지는 주 변화 (북이면 고른 수만)
+	visits   int           // 고른 상태-수를 지금까지 학습한 횟수
+	conf     float64       // 확신도 (moveConfidence)
+}
+
+// 확신도 계산의 기준값
+const (
+	confidenceVisits = 10.0 // 이만큼 학습한 상태-수면 학습량 쪽 확신도가 0.5
+	confidenceMargin = 10.0 // 다음 후보보다 이만큼(폰 하나) 앞서면 점수 차 쪽 확신도가 약 0.63
+)
+
+// 고른 수의 확신도(0~1)입니다. 학습 횟수 n으로 n/(n+10), 다음으로 좋은 후보와의 점수 차 margin으로
+// 1-exp(-margin/10)을 구해 곱합니다. 처음 보는 상태이거나 거의 같은 점수의 후보가 있으면 0에 가깝고,
+// 많이 학습했고 다른 수보다 확실히 좋을 때만 1에 가깝습니다. 탐색으로 더 낮은 점수의 수를 골랐으면 0입니다.
+func moveConfidence(visits int, margin float64) float64 {
+	if margin <= 0 {
+		return 0
+	}
+	n := float64(visits)
+	return n / (n + confidenceVisits) * (1 - math.Exp(-margin/confidenceMargin))
+}
+
+// 주어진 포지션에서 AI의 수를 고르고, 실제로 둔 수만 세션 기록에 남깁니다.
+// 오프닝 북에 있는 포지션이면 북의 수를 먼저 씁니다.
+// budget이 0보다 크면 search_depth 대신 그 시간 동안 반복 심화로 탐색합니다.
+// learnOn이 false면 Q테이블에 상태를 만들지 않고 세션의 학습 기록에도 남기지 않습니다.
+// (PGN용 대국 기록은 그대로 남깁니다.) 둘 수 있는 수가 없으면 move가 nil입니다.
+//
+// ai.mu는 탐색 전후로 잠깐만 잡습니다. 탐색 전에 Q값을 복사해 두고 탐색은 configMu 읽기 잠금만
+// 잡은 채로 하므로, 그동안 /stats 같은 읽기 요청과 다른 세션의 수 선택·학습이 기다리지 않습니다.
+// 탐색은 요청마다 따로 만든 pos만 씁니다. 세션의 대국 기록은 Position을 다른 요청과 함께 쓰므로
+// 기록이 필요한 무승부·반복 확인은 탐색 뒤 ai.mu 아래에서 합니다.
+func chooseMove(sessionID string, pos *chess.Position, budget time.Duration, learnOn bool) moveChoice {
+	moves := pos.ValidMoves()
 	if len(moves) == 0 {
-		return
+		return moveChoice{}
 	}
+	configMu.RLock()
+	defer configMu.RUnlock()
 
-	state := req.FEN
+	state := stateKey(pos)
 	ai.mu.Lock()
-	if ai.QTable[state] == nil {
+	if learnOn && ai.QTable[state] == nil {
 		ai.QTable[state] = make(map[string]float64)
+		markDirty(state)
+	}
+	syncSessionGame(sessionID, pos)
+	choice := moveChoice{source: "book"}
+	if ai.UseBook {
+		choice.move = bookMove(pos, ai.rng)
 	}
+	qrow := qValues(state)
+	ai.mu.Unlock()
 
-	// [학습 로직] QTable 점수 + 현재 보드의 기물 가치 점수를 합산하여 최선의 수 선택
-	sort.Slice(moves, func(i, j int) bool {
-		m1, m2 := moves[i], moves[j]
+	tt := make(transTable)
+	if choice.move == nil {
+		var stats searchStats
+		if budget > 0 {
+			choice.ranked, choice.depth = rankMovesTimed(pos, moves, qrow, tt, budget, &stats)
+		} else {
+			choice.ranked, choice.depth = rankMoves(pos, moves, qrow, tt, &stats), ai.SearchDepth
+		}
+		choice.nodes = stats.Nodes
+	}
 
-		// 각 수 이후의 보드 상태 점수 계산
-		g1, g2 := game.Clone(), game.Clone()
-		g1.Move(m1)
-		g2.Move(m2)
+	ai.mu.Lock()
+	// 탐색하는 동안 세션이 끝났거나 정리됐을 수 있으므로 다시 찾아 맞춥니다.
+	game := syncSessionGame(sessionID, pos)
+	margin := math.Inf(1) // 오프닝 북의 수이거나 후보가 하나뿐이면 점수 차는 무한대
+	if choice.move == nil {
+		applyContempt(pos, choice.ranked, qrow, game)
+		avoidRepetition(pos, choice.ranked, game.Positions())
+		cfg := ai.Config
+		cfg.Epsilon = cfg.effectiveEpsilon(ai.GameCount)
+		choice.move, choice.explored = pickMove(choice.ranked, moves, ai.rng, cfg)
+		choice.source = "search"
+		for _, s := range choice.ranked {
+			if s.move == choice.move {
+				choice.score = s.score
+			}
+		}
+		for _, s := range choice.ranked {
+			if s.move != choice.move {
+				margin = math.Min(margin, choice.score-s.score)
+			}
+		}
+	}
+	// 오프닝 북의 수는 점수를 매기지 않으므로 학습량만 봅니다.
+	choice.visits = ai.Visits[state][choice.move.String()]
+	choice.conf = moveConfidence(choice.visits, margin)
+	s := getSession(sessionID)
+	if learnOn {
+		s.record(pos, choice.move)
+	}
+	s.lastSeen = time.Now()
+	playMove(game, choice.move)
+	ai.mu.Unlock()
 
-		s1 := ai.QTable[state][m1.String()] + evaluateBoard(g1.Position())
-		s2 := ai.QTable[state][m2.String()] + evaluateBoard(g2.Position())
+	choice.pv = []*chess.Move{choice.move}
+	if choice.source == "search" {
+		choice.pv = append(choice.pv, tt.principalVariation(pos.Update(choice.move), choice.depth-1)...)
+	}
+	return choice
+}
 
-		return s1 > s2
-	})
+// 최고 점수와 이 값 이내로 차이 나는 수는 동점으로 봅니다.
+const tieEpsilon = 1e-6
+
+// 수 선택 방식 (Config.SelectionMode)
+const (
+	selectGreedy  = "greedy"  // 항상 최고 점수
+	selectEpsilon = "epsilon" // epsilon 확률로 무작위 수
+	selectSoftmax = "softmax" // 점수에 비례한 확률로 뽑기
+)
+
+// [탐색] 설정된 방식으로 수를 고릅니다. 최고 점수가 아닌 수를 골랐으면 true를 함께 반환합니다.
+func pickMove(ranked []scoredMove, moves []*chess.Move, r *rand.Rand, cfg Config) (*chess.Move, bool) {
+	switch cfg.SelectionMode {
+	case selectGreedy:
+		return topMove(ranked, r), false
+	case selectSoftmax:
+		return softmaxMove(ranked, r, cfg.Temperature)
+	}
+	// epsilon 확률로 무작위 합법 수를, 아니면 가장 점수가 높은 수를 고릅니다.
+	if r.Float64() < cfg.Epsilon {
+		return moves[r.Intn(len(moves))], true
+	}
+	return topMove(ranked, r), false
+}
+
+// 점수를 exp(score/T)에 비례한 확률로 바꿔 하나를 뽑습니다. (볼츠만 분포)
+// 좋은 수일수록 자주 탐색하고, 온도가 0에 가까우면 greedy, 크면 균등 선택에 가까워집니다.
+func softmaxMove(ranked []scoredMove, r *rand.Rand, temperature float64) (*chess.Move, bool) {
+	if temperature <= 0 {
+		return topMove(ranked, r), false
+	}
+	// 최고 점수를 빼서 exp가 넘치지 않게 합니다.
+	weights := make([]float64, len(ranked))
+	total := 0.0
+	for i, s := range ranked {
+		weights[i] = math.Exp((s.score - ranked[0].score) / temperature)
+		total += weights[i]
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x -= w; x < 0 {
+			return ranked[i].move, ranked[0].score-ranked[i].score > tieEpsilon
+		}
+	}
+	return ranked[0].move, false
+}
+
+// 정렬된 후보 중 최고 점수와 동점인 수들 가운데 하나를 고르게 뽑습니다.
+// 같은 포지션에서 매번 라이브러리 순서대로 같은 수만 두지 않게 합니다.
+func topMove(ranked []scoredMove, r *rand.Rand) *chess.Move {
+	n := 1
+	for n < len(ranked) && ranked[0].score-ranked[n].score <= tieEpsilon {
+		n++
+	}
+	return ranked[r.Intn(n)].move
+}
+
+// 수 기록에 "state|move|reward|"를 덧붙입니다. move는 승격 기물까지 붙은 UCI 표기(e7e8q, e7e8n)라
+// 승격 종류마다 따로 학습됩니다. 직전 수의 다음 상태(s')는 지금 AI가 받은 상태이므로 함께 채웁니다.
+func recordMove(history []string, pos *chess.Position, move *chess.Move) []string {
+	state := stateKey(pos)
+	if n := len(history); n > 0 && strings.HasSuffix(history[n-1], "|") {
+		history[n-1] += state
+	}
+	reward := strconv.FormatFloat(shapedReward(pos, move), 'g', -1, 64)
+	return append(history, state+"|"+move.String()+"|"+reward+"|")
+}
+
+// 수 기록 한 줄을 나눕니다. 형식이 맞지 않으면 ok가 false입니다.
+func parseRecord(record string) (state, move string, reward float64, next string, ok bool) {
+	parts := strings.Split(record, "|")
+	if len(parts) != 4 {
+		return "", "", 0, "", false
+	}
+	reward, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return "", "", 0, "", false
+	}
+	return parts[0], parts[1], reward, parts[3], true
+}
+
+// [학습] 수를 두기 전후의 평가값 차이로 주는 중간 보상입니다. (둔 쪽 관점)
+// 기물을 잡으면 양수, 잃으면 음수가 되어 종료 보상만 있을 때보다 학습 신호가 촘촘해집니다.
+// shaping_weight를 작게 두어 퀸 하나(90점)를 잡아도 종료 보상(±500)보다 훨씬 작게 합니다.
+// 평가의 체크·위협 가점(check_bonus, threat_bonus)도 이 차이에 들어가 체크나 위협을 만든 수가 조금 더 보상받습니다.
+func shapedReward(pos *chess.Position, move *chess.Move) float64 {
+	if ai.ShapingWeight == 0 {
+		return 0
+	}
+	before := evaluate(pos)
+	after := -evaluate(pos.Update(move))
+	// 수를 두면 차례가 넘어가 템포 가점(2×tempo)을 늘 잃습니다. 수의 좋고 나쁨과 무관하므로 되돌려 줍니다.
+	return ai.ShapingWeight * (after - before + 2*ai.Tempo)
+}
+
+// 한 판이 끝났을 때 각 세션(세션 ID → AI 색)의 기록으로 학습하고 세션을 지웁니다.
+// 세션마다 opponent 레이팅의 상대와 둔 것으로 보고 Elo 레이팅을 갱신합니다.
+// 세션마다 실제로 둔 수를 PGN으로 만들어 함께 돌려줍니다.
+// learnOn이 false면 레이팅과 PGN만 남기고 학습하지 않으며 학습한 판 수(GameCount)도 늘리지 않습니다.
+func finishGame(result, fen string, sides map[string]chess.Color, opponent float64, learnOn bool) (chess.Method, []string) {
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	if learnOn {
+		ai.GameCount++
+	}
+	method := chess.NoMethod
+	var pgns []string
+	for sessionID, aiColor := range sides {
+		var reward float64
+		reward, method = terminalReward(result, fen, aiColor)
+		var history []string
+		if s := ai.sessions[sessionID]; s != nil {
+			history = s.history
+		}
+		if learnOn {
+			learn(history, reward)
+		}
+		score := resultScore(result, fen, aiColor)
+		ai.Rating = updateElo(ai.Rating, opponent, score, ai.EloK)
+		recordResult(score)
+		slog.Info("대국 종료", "session", sessionID, "result", result, "color", aiColor.Name(),
+			"method", method.String(), "reward", reward, "moves", len(history), "learn", learnOn, "rating", ai.Rating)
+		if pgn := sessionPGN(sessionID, result, fen, aiColor, ai.GameCount); pgn != "" {
+			pgns = append(pgns, pgn)
+		}
+		dropSession(sessionID)
+	}
+	recordProgress()
+	return method, pgns
+}
 
-	selected := moves[0]
-	ai.MoveHistory = append(ai.MoveHistory, state+"|"+selected.String())
+// 학습한 Q테이블을 모두 지우고 처음부터 다시 학습합니다.
+// 실수로 지우지 않도록 본문에 {"confirm": "reset"}이 있어야 합니다.
+func resetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Confirm != "reset" {
+		writeError(w, http.StatusBadRequest, `confirm must be "reset"`)
+		return
+	}
+
+	ai.mu.Lock()
+	prevSize := len(ai.QTable)
+	ai.QTable = make(map[string]map[string]float64)
+	ai.QB = make(map[string]map[string]float64)
+	ai.Visits = make(map[string]map[string]int)
+	ai.replay = replayBuffer{}
+	ai.GameCount = 0
+	ai.Rating = defaultRating
+	ai.Results = ""
+	ai.Progress = nil
+	ai.sessions = make(map[string]*session)
+	needSnapshot()
 	ai.mu.Unlock()
+	saveToFile()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"move":       selected.String(),
-		"game_count": ai.GameCount,
-		"brain_size": len(ai.QTable),
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "reset",
+		"brain_size": prevSize,
 	})
 }
 
+// 학습 현황(판 수, 상태/항목 수, Q값 분포)을 돌려줍니다.
+// recent는 /move·스파링으로 끝난 최근 window판(기본 100, 최대 1000)의 승/무/패 비율입니다. (셀프 플레이 제외)
+// 값은 currentStats의 스냅숏에서 읽으므로 ai.mu를 잡지 않으며, statsMaxAge만큼 늦을 수 있습니다.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	window := defaultStatsWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > resultHistory {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("window must be between 1 and %d", resultHistory))
+			return
+		}
+		window = n
+	}
+	snap := currentStats()
+	stats := map[string]interface{}{
+		"game_count":   snap.GameCount,
+		"states":       snap.States,
+		"entries":      snap.Entries,
+		"total_visits": snap.TotalVisits,
+		"max_states":   snap.MaxStates,
+		"epsilon":      snap.Epsilon,
+		"alpha":        snap.Alpha,
+		"rating":       snap.Rating,
+		"recent":       recentResults(snap.Results, window),
+	}
+	if snap.Entries > 0 {
+		stats["min_q"] = snap.MinQ
+		stats["max_q"] = snap.MaxQ
+		stats["mean_q"] = snap.SumQ / float64(snap.Entries)
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// 주어진 FEN에서 학습된 Q값과 각 합법 수의 1수 평가 점수를 보여 줍니다.
+// 합산 점수가 높은 순으로 정렬합니다.
+func qvaluesHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FEN string `json:"fen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+	pos := game.Position()
+	state := stateKey(pos)
+
+	type moveInfo struct {
+		Move  string  `json:"move"`
+		Q     float64 `json:"q"`
+		Eval  float64 `json:"eval"`
+		Score float64 `json:"score"`
+	}
+	ai.mu.RLock()
+	qvalues := qValues(state)
+	var infos []moveInfo
+	for _, m := range pos.ValidMoves() {
+		q := qvalues[m.String()]
+		eval := -evaluate(pos.Update(m))
+		infos = append(infos, moveInfo{Move: m.String(), Q: q, Eval: eval, Score: blend(q, eval)})
+	}
+	ai.mu.RUnlock()
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Score > infos[j].Score
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state":   state,
+		"qvalues": qvalues,
+		"moves":   infos,
+	})
+}
+
+// POST /analyze {"fen": ...}: 포지션 평가를 항목별·색별로 나눠 보여 줍니다.
+// total은 evaluate 값(둘 차례인 쪽 관점)입니다. 상태를 바꾸지 않습니다.
+func analyzeHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FEN string `json:"fen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+	pos := game.Position()
+
+	ai.mu.RLock()
+	sides := evaluateSides(pos)
+	total := evaluate(pos)
+	phase := gamePhase(pos)
+	ai.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"turn":  pos.Turn().Name(),
+		"phase": phase,
+		"white": sides[chess.White],
+		"black": sides[chess.Black],
+		"total": total,
+	})
+}
+
+// POST /legal {"fen": ...}: 주어진 포지션의 합법 수를 UCI와 SAN으로 돌려줍니다.
+func legalHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FEN string `json:"fen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+
+	type legalMove struct {
+		UCI string `json:"uci"`
+		SAN string `json:"san"`
+	}
+	pos := game.Position()
+	moves := []legalMove{}
+	for _, m := range game.ValidMoves() {
+		moves = append(moves, legalMove{UCI: m.String(), SAN: chess.AlgebraicNotation{}.Encode(pos, m)})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"turn":  pos.Turn().Name(),
+		"moves": moves,
+	})
+}
+
+// GET /config
+// 현재 설정을 JSON으로 돌려줍니다. admin_token은 값 대신 설정 여부만 보여 줍니다.
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	ai.mu.RLock()
+	cfg := ai.Config
+	ai.mu.RUnlock()
+	writeJSON(w, http.StatusOK, redactConfig(cfg))
+}
+
+func redactConfig(cfg Config) Config {
+	if cfg.AdminToken != "" {
+		cfg.AdminToken = "********"
+	}
+	return cfg
+}
+
+// POST /config {"epsilon": 0.05, "search_depth": 4, ...}
+// 보낸 항목만 현재 설정 위에 덮어써 바로 반영하고, 바뀐 뒤의 설정을 돌려줍니다.
+// 알 수 없는 항목, 범위를 벗어난 값, 다시 시작해야 하는 항목(restartOnlyChanges)을 바꾸려 하면 400이며
+// 이때는 아무것도 바뀌지 않습니다. 설정 파일에는 쓰지 않습니다.
+func configUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read body: "+err.Error())
+		return
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	old := ai.Config
+	cfg := old
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid config: "+err.Error())
+		return
+	}
+	if err := cfg.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if fixed := restartOnlyChanges(old, cfg); len(fixed) > 0 {
+		writeError(w, http.StatusBadRequest, "cannot change at runtime: "+strings.Join(fixed, ", "))
+		return
+	}
+	ai.Config = cfg
+	if changes := configChanges(redactConfig(old), redactConfig(cfg)); len(changes) > 0 {
+		slog.Info("설정 변경", "changes", changes)
+	}
+	writeJSON(w, http.StatusOK, redactConfig(cfg))
+}
+
+// 플래그가 아닌 인자(파일 이름)를 모읍니다.
+// "-merge a.json b.json -out m.json"처럼 파일 이름 뒤에 오는 플래그도 읽습니다.
+func positionalArgs() []string {
+	var files []string
+	for args := flag.Args(); len(args) > 0; args = flag.Args() {
+		files = append(files, args[0])
+		flag.CommandLine.Parse(args[1:])
+	}
+	return files
+}
+
+// 환경 변수 값이 있으면 그 값을, 없으면 def를 돌려줍니다.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// 표준 오류로 내보내는 구조화 로거를 주어진 수준으로 설정합니다.
+// UCI 모드에서는 표준 출력을 프로토콜에 쓰므로 로그는 항상 표준 오류로 보냅니다.
+func setupLogging(level string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		slog.Warn("알 수 없는 로그 수준이라 info를 씁니다", "level", level)
+		lvl = slog.LevelInfo
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})))
+}
+
 func main() {
-	staticPath, _ := filepath.Abs("./static")
-	http.Handle("/", http.FileServer(http.Dir(staticPath)))
-	http.HandleFunc("/move", moveHandler)
-	http.HandleFunc("/save", func(w http.ResponseWriter, r *http.Request) {
-		saveToFile()
-		w.Write([]byte("OK"))
+	selfPlay := flag.Int("selfplay", 0, "프론트엔드 없이 N판의 셀프 플레이 학습만 하고 종료")
+	workers := flag.Int("workers", 0, "셀프 플레이 작업자 고루틴 수 (0이면 CPU 개수, 시드를 고정하면 1)")
+	uci := flag.Bool("uci", false, "HTTP 서버 대신 표준 입출력으로 UCI 엔진으로 동작")
+	logLevel := flag.String("log-level", envOr("LOG_LEVEL", "info"), "로그 수준: debug, info, warn, error (환경 변수 LOG_LEVEL)")
+	port := flag.Int("port", 8080, "HTTP 서버 포트")
+	staticDir := flag.String("static", "./static", "프론트엔드 정적 파일 디렉터리")
+	flag.StringVar(&qFile, "qfile", qFile, "Q테이블 파일 경로 (gzip 설정이면 .gz가 붙음)")
+	merge := flag.Bool("merge", false, "인자로 준 Q테이블 파일들을 합쳐 -out에 쓰고 종료")
+	mergeOut := flag.String("out", "merged.json", "-merge 결과 파일 (.gz면 압축)")
+	mergeMode := flag.String("merge-mode", mergeAverage, "-merge 방식: sum, average, max")
+	seed := flag.Int64("seed", 0, "난수 시드 (0이면 CHESS_SEED 환경 변수, 없으면 현재 시각)")
+	arena := flag.Bool("arena", false, "인자로 준 두 Q테이블(old new)을 -games판 대국시켜 비교하고 종료")
+	arenaGames := flag.Int("games", 10, "-arena 대국 수 (색을 번갈아 둠)")
+	perftMode := flag.Bool("perft", false, `인자로 준 "FEN 깊이"의 perft 노드 수를 찍고 종료 (FEN 대신 startpos 가능)`)
+	sparring := flag.Int("sparring", 0, "-stockfish 엔진을 상대로 N판을 두며 학습하고 종료")
+	stockfishPath := flag.String("stockfish", "stockfish", "-sparring 상대 UCI 엔진 실행 파일")
+	sfSkill := flag.Int("sf-skill", 5, "-sparring 상대의 Skill Level (0~20, 음수면 설정하지 않음)")
+	sfDepth := flag.Int("sf-depth", 8, "-sparring 상대의 탐색 깊이")
+	pprofOn := flag.Bool("pprof", false, "/debug/pprof/에 프로파일링 핸들러를 등록 (admin_token이 있으면 토큰 필요)")
+	sfRating := flag.Float64("sf-rating", defaultRating, "-sparring 상대의 대략적인 Elo 레이팅 (레이팅 갱신용)")
+	flag.Parse()
+	fixedSeed = *seed != 0 || os.Getenv("CHESS_SEED") != ""
+	if *seed == 0 {
+		*seed = loadSeed()
+	}
+	ai.rng = rand.New(rand.NewSource(*seed))
+	files := positionalArgs()
+	if *perftMode {
+		if err := runPerft(files, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *merge {
+		if err := runMerge(files, *mergeOut, *mergeMode); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	setupLogging(*logLevel)
+	slog.Debug("난수 시드", "seed", *seed)
+	if *arena {
+		if len(files) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: -arena old.json new.json [-games N]")
+			os.Exit(2)
+		}
+		ai.Config = loadConfig(configFile)
+		stats, err := runArena(files[0], files[1], *arenaGames, ai.rng)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	loadSettings()
+	if *uci || *sparring > 0 || *selfPlay > 0 {
+		loadBrain()
+	}
+	if *uci {
+		runUCI(os.Stdin, os.Stdout)
+		return
+	}
+	if *sparring > 0 {
+		eng := stockfish.New(*stockfishPath, *sfSkill, *sfDepth)
+		defer eng.Close()
+		stats, err := runSparring(eng, *sparring, *sfRating)
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+		if err != nil {
+			slog.Error("스파링 중단", "err", err)
+		}
+		return
+	}
+	if *selfPlay > 0 {
+		stats := runSelfPlay(*selfPlay, *workers)
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	staticPath, _ := filepath.Abs(*staticDir)
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(staticPath)))
+	// 탐색은 CPU를 많이 쓰고 관리용 엔드포인트는 더 드물어야 하므로 IP마다 따로 제한합니다.
+	moveLimit := newRateLimiter(ai.RateLimit, ai.RateBurst, ai.TrustProxy)
+	adminLimit := newRateLimiter(ai.AdminRateLimit, ai.AdminRateBurst, ai.TrustProxy)
+	// 학습된 Q테이블을 바꾸거나 지우는 엔드포인트는 admin_token이 있으면 토큰을 요구합니다.
+	token := adminToken()
+	admin := func(h http.HandlerFunc) http.HandlerFunc {
+		return adminLimit.wrap(requireAdmin(token, h))
+	}
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/ready", readyHandler)
+	mux.HandleFunc("/move", moveLimit.wrap(moveHandler))
+	mux.HandleFunc("/ws", moveLimit.wrap(wsHandler(moveLimit)))
+	updateConfig := admin(configUpdateHandler)
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			updateConfig(w, r)
+			return
+		}
+		configHandler(w, r)
 	})
-	fmt.Println("서버 시작: http://localhost:8080")
-	http.ListenAndServe(":8080", nil)
+	mux.HandleFunc("/reset", admin(resetHandler))
+	mux.HandleFunc("/stats", statsHandler)
+	mux.HandleFunc("/progress", progressHandler)
+	mux.HandleFunc("/qvalues", qvaluesHandler)
+	mux.HandleFunc("/analyze", analyzeHandler)
+	mux.HandleFunc("/evaluate/batch", moveLimit.wrap(evaluateBatchHandler))
+	mux.HandleFunc("/legal", legalHandler)
+	mux.HandleFunc("/solve", moveLimit.wrap(solveHandler))
+	mux.HandleFunc("/render", renderHandler)
+	mux.HandleFunc("/selfplay", admin(selfPlayHandler))
+	mux.HandleFunc("/games", gamesHandler)
+	mux.HandleFunc("/session", sessionHandler)
+	mux.HandleFunc("/newgame", moveLimit.wrap(newGameHandler))
+	mux.HandleFunc("/replay", replayHandler)
+	mux.HandleFunc("/train/pgn", admin(trainPGNHandler))
+	mux.HandleFunc("/prune", admin(pruneHandler))
+	mux.HandleFunc("/export", exportHandler)
+	mux.HandleFunc("/import", admin(importHandler))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/save", admin(func(w http.ResponseWriter, r *http.Request) {
+		if err := saveToFile(); err != nil {
+			writeError(w, http.StatusInternalServerError, "save failed: "+err.Error())
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	if *pprofOn {
+		registerPprof(mux, token)
+	}
+	go cleanupSessions(time.Minute)
+	if ai.AutosaveSecs > 0 {
+		go autosave(time.Duration(ai.AutosaveSecs) * time.Second)
+	}
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", *port), Handler: withCORS(ai.CORSOrigin, mux)}
+	srv.RegisterOnShutdown(closeWebSockets)
+	done := make(chan struct{})
+	go shutdownOnSignal(srv, done)
+
+	ai.mu.Lock()
+	go loadBrainLocked()
+	slog.Info("서버 시작", "addr", fmt.Sprintf("http://localhost:%d", *port), "static", staticPath, "qfile", qPath(), "admin_auth", token != "")
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		slog.Error("서버 오류", "err", err)
+		return
+	}
+	<-done
 }
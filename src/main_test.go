@@ -0,0 +1,868 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestMain(m *testing.M) {
+	// 요청 실패 등 로그가 테스트 출력을 덮지 않게 합니다.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// 테스트마다 기본 설정과 빈 Q테이블의 새 ai로 시작하고, 끝나면 원래 값으로 되돌립니다.
+// 오프닝 북과 무작위 탐색(epsilon)은 끄고 난수 시드는 고정하며, Q테이블 파일은 임시 폴더에 씁니다.
+func newTestAI(t testing.TB) {
+	t.Helper()
+	old, oldFile, oldPGN, oldReady := ai, qFile, pgnFile, brainReady.Load()
+	ai = &ChessAI{
+		Config:   defaultConfig(),
+		QTable:   make(map[string]map[string]float64),
+		QB:       make(map[string]map[string]float64),
+		Visits:   make(map[string]map[string]int),
+		Rating:   defaultRating,
+		sessions: make(map[string]*session),
+		dirty:    make(map[string]struct{}),
+		rng:      rand.New(rand.NewSource(1)),
+	}
+	ai.UseBook = false
+	ai.Epsilon = 0
+	qFile = filepath.Join(t.TempDir(), "qtable.json")
+	pgnFile = filepath.Join(filepath.Dir(qFile), "games.pgn") // 테스트 대국이 저장소의 기보에 섞이지 않게 합니다
+	brainReady.Store(true)
+	statsCache.Store(nil) // 이전 ai의 /stats 스냅숏을 버립니다
+	t.Cleanup(func() {
+		ai, qFile, pgnFile = old, oldFile, oldPGN
+		brainReady.Store(oldReady)
+		statsCache.Store(nil)
+	})
+}
+
+// FEN으로 포지션을 만듭니다.
+func mustPos(t testing.TB, fen string) *chess.Position {
+	t.Helper()
+	game, err := parseFEN(fen)
+	if err != nil {
+		t.Fatalf("parseFEN(%q): %v", fen, err)
+	}
+	return game.Position()
+}
+
+// UCI 표기로 수를 찾습니다.
+func mustMove(t testing.TB, pos *chess.Position, uci string) *chess.Move {
+	t.Helper()
+	m, err := chess.UCINotation{}.Decode(pos, uci)
+	if err != nil {
+		t.Fatalf("decode %s in %s: %v", uci, pos, err)
+	}
+	return m
+}
+
+func TestChooseMoveSearchesWithoutLock(t *testing.T) {
+	newTestAI(t)
+	pos := mustPos(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	done := make(chan moveChoice)
+	go func() {
+		done <- chooseMove("s", pos, 300*time.Millisecond, true)
+	}()
+
+	// 탐색이 도는 동안에도 쓰기 잠금을 바로 잡을 수 있어야 합니다.
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	ai.mu.Lock()
+	waited := time.Since(start)
+	ai.mu.Unlock()
+	choice := <-done
+
+	if waited > 100*time.Millisecond {
+		t.Errorf("ai.mu was held during search: waited %v", waited)
+	}
+	if choice.move == nil || choice.source != "search" || choice.depth < 1 {
+		t.Fatalf("choice = %+v", choice)
+	}
+	ai.mu.RLock()
+	defer ai.mu.RUnlock()
+	if s := ai.sessions["s"]; s == nil || len(s.history) != 1 || len(s.game.Moves()) != 1 {
+		t.Errorf("session not recorded: %+v", s)
+	}
+}
+
+// 같은 세션에 /move가 동시에 와도 세션의 대국 기록(Position)을 잠금 없이 건드리지 않아야 합니다.
+// (go test -race로 돌리면 확인됩니다)
+func TestConcurrentMovesOnSameSession(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	const fen = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	// 세션의 대국 기록을 먼저 만들어 둡니다.
+	chooseMove("s", mustPos(t, fen), 0, true)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if choice := chooseMove("s", mustPos(t, fen), 0, true); choice.move == nil {
+				t.Error("no move chosen")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 수가 많은 미들게임 포지션 (벤치마크용)
+const middlegameFEN = "r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 8"
+
+// 예전 선택 방식처럼 정렬 비교 함수 안에서 매번 대국을 복사해 두 수를 두고 점수를 매기면
+// 수마다 O(log n)번씩 복사하고 평가합니다. 점수를 한 번씩만 매기는 rankAtDepth(깊이 1)와
+// op당 평가한 노드 수(nodes/op)와 대국 복사 횟수(clones/op)를 비교합니다. 점수는 양쪽 모두 같은 탐색입니다.
+func BenchmarkRankMoves(b *testing.B) {
+	newTestAI(b)
+	game, _ := parseFEN(middlegameFEN)
+	pos := game.Position()
+	moves := pos.ValidMoves()
+	inf := math.Inf(1)
+	b.Run("comparator", func(b *testing.B) {
+		var stats searchStats
+		tt := make(transTable)
+		clones := 0
+		for i := 0; i < b.N; i++ {
+			ms := append([]*chess.Move(nil), moves...)
+			sort.Slice(ms, func(i, j int) bool {
+				g1, g2 := game.Clone(), game.Clone()
+				g1.Move(ms[i])
+				g2.Move(ms[j])
+				clones += 2
+				s1, _ := tt.searchUntil(g1.Position(), 0, 1, -inf, inf, time.Time{}, &stats)
+				s2, _ := tt.searchUntil(g2.Position(), 0, 1, -inf, inf, time.Time{}, &stats)
+				return -s1 > -s2
+			})
+		}
+		b.ReportMetric(float64(stats.Nodes)/float64(b.N), "nodes/op")
+		b.ReportMetric(float64(clones)/float64(b.N), "clones/op")
+	})
+	b.Run("precomputed", func(b *testing.B) {
+		var stats searchStats
+		tt := make(transTable)
+		for i := 0; i < b.N; i++ {
+			clear(tt)
+			rankAtDepth(pos, moves, nil, tt, 1, time.Time{}, &stats)
+		}
+		b.ReportMetric(float64(stats.Nodes)/float64(b.N), "nodes/op")
+		b.ReportMetric(0, "clones/op")
+	})
+}
+
+func TestNormalizeFENIgnoresMoveCounters(t *testing.T) {
+	a := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	b := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 7 31"
+	if normalizeFEN(a) != normalizeFEN(b) {
+		t.Errorf("normalizeFEN differs: %q vs %q", normalizeFEN(a), normalizeFEN(b))
+	}
+	if got := stateKey(mustPos(t, a)); got != stateKey(mustPos(t, b)) {
+		t.Errorf("stateKey differs for %q", got)
+	}
+	// 차례가 다르면 다른 상태입니다.
+	c := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2"
+	if normalizeFEN(a) == normalizeFEN(c) {
+		t.Errorf("side to move was dropped from key %q", normalizeFEN(a))
+	}
+}
+
+func TestMigrateStateKeysMergesCounters(t *testing.T) {
+	newTestAI(t)
+	a := "8/8/8/4k3/8/8/4K3/8 w - - 0 40"
+	b := "8/8/8/4k3/8/8/4K3/8 w - - 3 57"
+	ai.QTable[a] = map[string]float64{"e2e3": 1}
+	ai.QTable[b] = map[string]float64{"e2e3": 3}
+	ai.Visits[a] = map[string]int{"e2e3": 2}
+	ai.Visits[b] = map[string]int{"e2e3": 5}
+	migrateStateKeys()
+
+	key := normalizeFEN(a)
+	if len(ai.QTable) != 1 || ai.QTable[key]["e2e3"] != 2 {
+		t.Errorf("QTable = %v, want averaged 2 under %q", ai.QTable, key)
+	}
+	if ai.Visits[key]["e2e3"] != 7 {
+		t.Errorf("Visits = %v, want summed 7", ai.Visits)
+	}
+}
+
+// 상태 n개, 상태마다 수 몇 개의 Q값이 든 테이블을 만듭니다.
+func populateQTable(n int) {
+	r := rand.New(rand.NewSource(2))
+	game := chess.NewGame()
+	for i := 0; len(ai.QTable) < n && i < 10*n; i++ {
+		pos := game.Position()
+		moves := pos.ValidMoves()
+		if len(moves) == 0 || len(game.Moves()) > 80 {
+			game = chess.NewGame()
+			continue
+		}
+		row := make(map[string]float64)
+		for _, m := range moves[:min(len(moves), 5)] {
+			row[m.String()] = r.NormFloat64()
+		}
+		ai.QTable[stateKey(pos)] = row
+		game.Move(moves[r.Intn(len(moves))])
+	}
+}
+
+func TestGzipSnapshotIsSmallerAndRoundTrips(t *testing.T) {
+	newTestAI(t)
+	ai.Journal = false
+	populateQTable(1000)
+	want := len(ai.QTable)
+
+	ai.Gzip = false
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	ai.Gzip = true
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	plain, err1 := os.Stat(qFile)
+	gz, err2 := os.Stat(qFile + ".gz")
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	t.Logf("%d states: plain %d bytes, gzip %d bytes (%.1f%%)", want, plain.Size(), gz.Size(), 100*float64(gz.Size())/float64(plain.Size()))
+	if gz.Size()*2 > plain.Size() {
+		t.Errorf("gzip %d bytes is not much smaller than plain %d bytes", gz.Size(), plain.Size())
+	}
+
+	ai.QTable = nil
+	loadFromFile()
+	if len(ai.QTable) != want {
+		t.Errorf("loaded %d states from gzip, want %d", len(ai.QTable), want)
+	}
+}
+
+func TestPartialWriteKeepsOldQTable(t *testing.T) {
+	newTestAI(t)
+	ai.Journal, ai.Gzip = false, false
+	ai.QTable["s"] = map[string]float64{"e2e4": 1}
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile(qPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 새 내용을 절반쯤 쓰다가 디스크가 가득 찬 것처럼 실패시킵니다.
+	ai.QTable["s"]["d2d4"] = 2
+	data, _ := json.Marshal(ai)
+	errFull := errors.New("no space left on device")
+	err = writeAtomic(qPath(), func(w io.Writer) error {
+		if _, err := w.Write(data[:len(data)/2]); err != nil {
+			return err
+		}
+		return errFull
+	})
+	if !errors.Is(err, errFull) {
+		t.Fatalf("writeAtomic err = %v, want %v", err, errFull)
+	}
+	if got, err := os.ReadFile(qPath()); err != nil || !bytes.Equal(got, old) {
+		t.Errorf("qtable after failed write changed (err %v):\n%s", err, got)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(qPath()), "*.tmp")); len(tmps) > 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+}
+
+func TestMoveHandlerRejectsInvalidFEN(t *testing.T) {
+	newTestAI(t)
+	tests := []struct {
+		name, fen string
+	}{
+		{"empty", ""},
+		{"truncated", "rnbqkbnr/pppppppp/8/8"},
+		{"illegal piece", "rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{"wrong rank count", "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{"missing king", "rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1"},
+	}
+	for _, tt := range tests {
+		if _, err := parseFEN(tt.fen); err == nil {
+			t.Errorf("%s: parseFEN(%q) succeeded", tt.name, tt.fen)
+		}
+		body, _ := json.Marshal(map[string]string{"fen": tt.fen, "session_id": "s"})
+		rec := httptest.NewRecorder()
+		moveHandler(rec, httptest.NewRequest(http.MethodPost, "/move", bytes.NewReader(body)))
+		var resp map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusBadRequest || !strings.HasPrefix(resp["error"], "invalid FEN: ") {
+			t.Errorf("%s: status %d, body %s", tt.name, rec.Code, rec.Body)
+		}
+	}
+}
+
+// 점수가 scores인 후보 목록을 시작 포지션의 합법 수로 만듭니다.
+func rankedWithScores(t testing.TB, scores ...float64) []scoredMove {
+	t.Helper()
+	moves := chess.StartingPosition().ValidMoves()
+	ranked := make([]scoredMove, len(scores))
+	for i, s := range scores {
+		ranked[i] = scoredMove{move: moves[i], score: s}
+	}
+	return ranked
+}
+
+func TestTopMoveBreaksTiesRandomly(t *testing.T) {
+	ranked := rankedWithScores(t, 5, 5, 5, 1)
+	picked := make(map[string]bool)
+	for seed := int64(0); seed < 50; seed++ {
+		m := topMove(ranked, rand.New(rand.NewSource(seed)))
+		if m == ranked[3].move {
+			t.Fatalf("seed %d picked the lower-scored move %s", seed, m)
+		}
+		picked[m.String()] = true
+		// 같은 시드면 같은 수를 고릅니다.
+		if again := topMove(ranked, rand.New(rand.NewSource(seed))); again != m {
+			t.Errorf("seed %d not reproducible: %s then %s", seed, m, again)
+		}
+	}
+	if len(picked) != 3 {
+		t.Errorf("picked %v, want all three tied moves", picked)
+	}
+}
+
+func TestAvoidRepetitionWhenWinning(t *testing.T) {
+	newTestAI(t)
+	pos := mustPos(t, "7k/8/8/8/8/8/8/Q3K3 w - - 0 1") // 퀸이 앞섬
+	back, fresh := mustMove(t, pos, "a1a2"), mustMove(t, pos, "a1b2")
+	history := []*chess.Position{pos.Update(back)} // a1a2 뒤의 포지션이 이미 나왔음
+
+	ranked := []scoredMove{{move: back, score: 10}, {move: fresh, score: 10 - ai.RepetitionPenalty/2}}
+	avoidRepetition(pos, ranked, history)
+	if ranked[0].move != fresh {
+		t.Errorf("winning side repeated: %s first (scores %v, %v)", ranked[0].move, ranked[0].score, ranked[1].score)
+	}
+
+	// 지고 있으면 반복(무승부)이 이득이므로 그대로 둡니다.
+	losing := mustPos(t, "7k/8/8/8/8/8/8/q3K3 w - - 0 1")
+	back, fresh = mustMove(t, losing, "e1e2"), mustMove(t, losing, "e1f2")
+	ranked = []scoredMove{{move: back, score: 10}, {move: fresh, score: 9}}
+	avoidRepetition(losing, ranked, []*chess.Position{losing.Update(back)})
+	if ranked[0].move != back || ranked[0].score != 10 {
+		t.Errorf("losing side was penalized: %+v", ranked[0])
+	}
+}
+
+func TestContemptScoresRepetitionBelowAlternative(t *testing.T) {
+	newTestAI(t)
+	ai.Contempt = 100
+	opt, _ := chess.FEN("7k/8/8/8/8/8/8/1Q2K3 w - - 0 1")
+	game := chess.NewGame(opt)
+	for _, uci := range []string{"b1c1", "h8g8", "c1b1", "g8h8", "b1c1", "h8g8", "c1b1", "g8h8"} {
+		if err := game.Move(mustMove(t, game.Position(), uci)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos := game.Position()
+	repeat, other := mustMove(t, pos, "b1c1"), mustMove(t, pos, "b1d3") // b1c1은 세 번째 반복
+	ranked := []scoredMove{{move: repeat, score: 50, eval: 50}, {move: other, score: 40, eval: 40}}
+	applyContempt(pos, ranked, nil, game)
+	if ranked[0].move != other {
+		t.Errorf("repetition kept first: %s %v, %s %v", ranked[0].move, ranked[0].score, ranked[1].move, ranked[1].score)
+	}
+	if ranked[1].eval != -ai.Contempt {
+		t.Errorf("repetition eval = %v, want %v", ranked[1].eval, -ai.Contempt)
+	}
+}
+
+func TestIllegalMoveIsSkipped(t *testing.T) {
+	newTestAI(t)
+	game := chess.NewGame()
+	pos := game.Position()
+	// 다른 포지션(흑 차례)의 수를 후보에 끼워 넣습니다. 대국에는 둘 수 없습니다.
+	black := mustPos(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	illegal := mustMove(t, black, "e7e5")
+	legal := mustMove(t, pos, "d2d4")
+
+	if playMove(game, illegal) {
+		t.Fatal("playMove accepted an illegal move")
+	}
+	if len(game.Moves()) != 0 || game.Position().String() != pos.String() {
+		t.Fatalf("game changed after a rejected move: %s", game.Position())
+	}
+
+	ranked := []scoredMove{{move: illegal, score: 100, eval: 100}, {move: legal, score: 1, eval: 1}}
+	applyContempt(pos, ranked, nil, game)
+	if ranked[0].move != legal {
+		t.Errorf("illegal move still ranked first: %s", ranked[0].move)
+	}
+	if ranked[1].score != illegalScore || ranked[1].eval != illegalScore {
+		t.Errorf("illegal move scored %v/%v, want %v", ranked[1].score, ranked[1].eval, illegalScore)
+	}
+}
+
+func TestKnightUnderpromotionMate(t *testing.T) {
+	newTestAI(t)
+	// f8=N만 메이트입니다. (f8=Q는 g8 비숍에 막혀 체크가 아님)
+	fen := "6br/5Ppk/6pp/8/8/8/8/K7 w - - 0 1"
+	pos := mustPos(t, fen)
+	ranked := rankMoves(pos, pos.ValidMoves(), nil, make(transTable), nil)
+	if got := ranked[0].move.String(); got != "f7f8n" {
+		t.Fatalf("best move %s, want f7f8n", got)
+	}
+
+	rec := postJSON(t, moveHandler, "/move", map[string]interface{}{"fen": fen, "color": "white", "session_id": "promo"})
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp["move"] != "f7f8n" || resp["san"] != "f8=N#" {
+		t.Errorf("move %v san %v, want f7f8n f8=N#", resp["move"], resp["san"])
+	}
+	// 학습 기록에는 승격 기물까지 붙은 UCI로 남아 다른 승격과 따로 배웁니다.
+	if h := ai.sessions["promo"].history; len(h) != 1 || !strings.Contains(h[0], "|f7f8n|") {
+		t.Errorf("history = %v, want the f7f8n record", h)
+	}
+}
+
+func TestSoftmaxTemperature(t *testing.T) {
+	ranked := rankedWithScores(t, 10, 5, 0, -5)
+	counts := func(temperature float64) map[string]int {
+		r := rand.New(rand.NewSource(3))
+		n := make(map[string]int)
+		for i := 0; i < 4000; i++ {
+			m, _ := softmaxMove(ranked, r, temperature)
+			n[m.String()]++
+		}
+		return n
+	}
+
+	// 온도가 낮으면 거의 항상 최고 점수의 수를 고릅니다.
+	if n := counts(0.1)[ranked[0].move.String()]; n < 3990 {
+		t.Errorf("low temperature picked the top move %d/4000 times", n)
+	}
+	// 온도가 높으면 거의 고르게 고릅니다.
+	high := counts(1000)
+	for _, s := range ranked {
+		if n := high[s.move.String()]; n < 850 || n > 1150 {
+			t.Errorf("high temperature picked %s %d/4000 times, want about 1000", s.move, n)
+		}
+	}
+	// 온도가 0이면 greedy와 같습니다.
+	if m, explored := softmaxMove(ranked, rand.New(rand.NewSource(1)), 0); m != ranked[0].move || explored {
+		t.Errorf("zero temperature picked %s (explored %v)", m, explored)
+	}
+}
+
+func TestAdaptiveAlphaUsesVisitCounts(t *testing.T) {
+	newTestAI(t)
+	ai.AdaptiveAlpha, ai.DoubleQ, ai.Augment, ai.Lambda, ai.NStep = true, false, false, 0, 1
+	tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	visits := make(map[string]map[string]int)
+	history := []string{"s|e2e4|0|"}
+	r := rand.New(rand.NewSource(1))
+
+	// 처음 배울 때는 학습률 1/(1+0)=1로 보상을 그대로 배웁니다.
+	learnInto(tables, visits, history, 10, 0.01, r)
+	if q, n := tables.a["s"]["e2e4"], visits["s"]["e2e4"]; q != 10 || n != 1 {
+		t.Fatalf("after 1 visit: q=%v visits=%d, want 10 and 1", q, n)
+	}
+	// 두 번째는 1/(1+1)로 절반만 옮겨 갑니다.
+	tables.a["s"]["e2e4"] = 0
+	learnInto(tables, visits, history, 10, 0.01, r)
+	if q, n := tables.a["s"]["e2e4"], visits["s"]["e2e4"]; q != 5 || n != 2 {
+		t.Errorf("after 2 visits: q=%v visits=%d, want 5 and 2", q, n)
+	}
+	// 많이 본 항목도 alpha 아래로는 내려가지 않습니다.
+	if got := learningRate(1000, 0.01); got != 0.01 {
+		t.Errorf("learningRate(1000) = %v, want the 0.01 floor", got)
+	}
+	ai.AdaptiveAlpha = false
+	if got := learningRate(0, 0.1); got != 0.1 {
+		t.Errorf("fixed learningRate = %v, want 0.1", got)
+	}
+}
+
+func TestVisitsPersistAndShowInStats(t *testing.T) {
+	newTestAI(t)
+	ai.Journal = false
+	ai.QTable["s"] = map[string]float64{"e2e4": 1}
+	ai.Visits["s"] = map[string]int{"e2e4": 3, "d2d4": 4}
+	if err := saveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	ai.Visits = nil
+	loadFromFile()
+	if ai.Visits["s"]["e2e4"] != 3 || ai.Visits["s"]["d2d4"] != 4 {
+		t.Errorf("visits after reload = %v", ai.Visits)
+	}
+	if total := getStats(t)["total_visits"]; total != 7.0 {
+		t.Errorf("total_visits = %v, want 7", total)
+	}
+}
+
+func TestShapedRewardFavorsQueenCapture(t *testing.T) {
+	newTestAI(t)
+	pos := mustPos(t, "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	capture := shapedReward(pos, mustMove(t, pos, "d2d5"))
+	quiet := shapedReward(pos, mustMove(t, pos, "e1f1"))
+	if capture <= quiet || capture <= 0 {
+		t.Errorf("queen capture reward %v, quiet move %v", capture, quiet)
+	}
+	// 퀸을 잡아도 종료 보상보다 훨씬 작습니다.
+	if capture >= ai.WinReward/2 {
+		t.Errorf("queen capture reward %v rivals the terminal reward %v", capture, ai.WinReward)
+	}
+	// 기록에 남은 중간 보상이 그 수의 전이 보상에 들어갑니다.
+	history := recordMove(nil, pos, mustMove(t, pos, "d2d5"))
+	steps := nStepTransitions(history, 0, 1, ai.Gamma)
+	if len(steps) != 1 || steps[0].reward != capture {
+		t.Errorf("transitions = %+v, want reward %v", steps, capture)
+	}
+}
+
+// 핸들러에 JSON 본문을 POST로 보냅니다.
+func postJSON(t testing.TB, h http.HandlerFunc, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	return rec
+}
+
+func TestSANForCastlingAndPromotion(t *testing.T) {
+	newTestAI(t)
+	rec := postJSON(t, legalHandler, "/legal", map[string]string{"fen": "8/4P3/k7/8/8/8/8/4K2R w K - 0 1"})
+	var legal struct {
+		Moves []struct{ UCI, SAN string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &legal); err != nil {
+		t.Fatal(err)
+	}
+	san := make(map[string]string)
+	for _, m := range legal.Moves {
+		san[m.UCI] = m.SAN
+	}
+	for uci, want := range map[string]string{"e1g1": "O-O", "e7e8q": "e8=Q", "e7e8n": "e8=N", "h1h5": "Rh5"} {
+		if san[uci] != want {
+			t.Errorf("SAN of %s = %q, want %q", uci, san[uci], want)
+		}
+	}
+
+	// /move 응답에도 UCI와 SAN이 함께 들어가고, 학습 기록의 키는 UCI 그대로입니다.
+	// 흑 왕이 폰을 노리고 있어 바로 승진하는 수가 유일한 최선입니다. (미루면 같은 포지션으로 이어져 동점이 됩니다)
+	rec = postJSON(t, moveHandler, "/move", map[string]interface{}{"fen": "8/4P3/3k4/8/8/8/8/4K3 w - - 0 1", "session_id": "s", "time_ms": 100})
+	var move map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &move)
+	if move["uci"] != "e7e8q" || move["san"] != "e8=Q" {
+		t.Errorf("move response uci %v san %v, want e7e8q e8=Q", move["uci"], move["san"])
+	}
+	if h := ai.sessions["s"].history; len(h) != 1 || !strings.Contains(h[0], "|e7e8q|") {
+		t.Errorf("session history = %v", h)
+	}
+}
+
+func TestLargerTimeBudgetSearchesDeeper(t *testing.T) {
+	newTestAI(t)
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	depth := func(ms int) float64 {
+		rec := postJSON(t, moveHandler, "/move", map[string]interface{}{"fen": fen, "color": "white", "time_ms": ms, "learn": false})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp["depth_reached"].(float64)
+	}
+	// 깊이 1은 시간이 지나도 끝까지 봅니다.
+	short, long := depth(1), depth(2000)
+	if short != 1 {
+		t.Errorf("1ms budget reached depth %v, want 1", short)
+	}
+	if long <= short {
+		t.Errorf("2s budget reached depth %v, want more than %v", long, short)
+	}
+}
+
+func TestSARSAUsesTakenNextMove(t *testing.T) {
+	newTestAI(t)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment, ai.Lambda, ai.NStep, ai.Gamma = false, false, false, 0, 1, 0.9
+	// s1에서 m1을 두고, s2에서는 Q값이 낮은 m2를 실제로 두었습니다. (s2의 최고 수는 x)
+	history := []string{"s1|m1|0|s2", "s2|m2|0|"}
+	run := func(algo string) float64 {
+		ai.LearningAlgo = algo
+		tables := qTables{a: map[string]map[string]float64{"s2": {"m2": 1, "x": 10}}, b: make(map[string]map[string]float64)}
+		learnInto(tables, make(map[string]map[string]int), history, 0, 0.5, rand.New(rand.NewSource(1)))
+		// 뒤에서부터 갱신하므로 Q(s2,m2)는 먼저 1 + 0.5(0-1) = 0.5가 됩니다.
+		if q := tables.a["s2"]["m2"]; q != 0.5 {
+			t.Fatalf("%s: Q(s2,m2) = %v, want 0.5", algo, q)
+		}
+		return tables.a["s1"]["m1"]
+	}
+	// Q-learning: 0.5 × 0.9 × max(0.5, 10)
+	if got, want := run(algoQLearning), 0.5*0.9*10; math.Abs(got-want) > 1e-9 {
+		t.Errorf("qlearning Q(s1,m1) = %v, want %v", got, want)
+	}
+	// SARSA: 0.5 × 0.9 × Q(s2,m2)
+	if got, want := run(algoSARSA), 0.5*0.9*0.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("sarsa Q(s1,m1) = %v, want %v", got, want)
+	}
+}
+
+func TestNStepTargets(t *testing.T) {
+	newTestAI(t)
+	ai.Decay = 0.5
+	const gamma, reward = 0.9, 8.0
+	// 중간 보상 1, 2, 3, 4, 5인 다섯 수. 종료 보상 8은 끝에서부터 0.5배씩 줄어 더해집니다.
+	history := []string{"s0|m0|1|s1", "s1|m1|2|s2", "s2|m2|3|s3", "s3|m3|4|s4", "s4|m4|5|"}
+	r := []float64{1 + 8*0.0625, 2 + 8*0.125, 3 + 8*0.25, 4 + 8*0.5, 5 + 8}
+
+	steps := nStepTransitions(history, reward, 3, gamma)
+	if len(steps) != 5 {
+		t.Fatalf("got %d transitions, want 5", len(steps))
+	}
+	// 처음 두 전이는 세 수의 할인 보상 + γ³Q(3수 뒤 상태, 그 상태에서 둔 수)입니다.
+	for i, want := range []transition{
+		{state: "s0", move: "m0", next: "s3", nextMove: "m3", steps: 3, reward: r[0] + gamma*r[1] + gamma*gamma*r[2]},
+		{state: "s1", move: "m1", next: "s4", nextMove: "m4", steps: 3, reward: r[1] + gamma*r[2] + gamma*gamma*r[3]},
+		// 세 수 안에 판이 끝나면 남은 보상만 더하고 부트스트랩하지 않습니다.
+		{state: "s2", move: "m2", steps: 3, reward: r[2] + gamma*r[3] + gamma*gamma*r[4]},
+		{state: "s3", move: "m3", steps: 3, reward: r[3] + gamma*r[4]},
+		{state: "s4", move: "m4", steps: 3, reward: r[4]},
+	} {
+		got := steps[i]
+		if math.Abs(got.reward-want.reward) > 1e-9 {
+			t.Errorf("step %d reward = %v, want %v", i, got.reward, want.reward)
+		}
+		got.reward = want.reward
+		if got != want {
+			t.Errorf("step %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// 갱신 목표: Q(s0,m0) ← G + γ³·Q(s3,m3)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment, ai.Gamma = false, false, false, gamma
+	tables := qTables{a: map[string]map[string]float64{"s3": {"m3": 10}}, b: make(map[string]map[string]float64)}
+	updateQ(tables, steps[0], 1, rand.New(rand.NewSource(1)))
+	if got, want := tables.a["s0"]["m0"], steps[0].reward+gamma*gamma*gamma*10; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Q(s0,m0) = %v, want %v", got, want)
+	}
+}
+
+func TestServerDetectsDraws(t *testing.T) {
+	newTestAI(t)
+	for _, c := range []struct {
+		name, fen, sent, want string
+		method                chess.Method
+	}{
+		{"K vs K", "8/8/8/4k3/8/8/8/4K3 w - - 0 60", "White", "Draw", chess.InsufficientMaterial},
+		{"K+B vs K", "8/8/8/4k3/8/8/8/2B1K3 b - - 0 60", "Black", "Draw", chess.InsufficientMaterial},
+		{"fifty moves", "8/8/8/4k3/8/8/8/R3K3 b - - 100 80", "White", "Draw", chess.FiftyMoveRule},
+		// 한 반수 모자라면 아직 청구할 수 없어 보낸 결과를 그대로 씁니다.
+		{"one ply short", "8/8/8/4k3/8/8/8/R3K3 b - - 99 80", "White", "White", chess.NoMethod},
+	} {
+		result, game := judgeResult(c.sent, c.fen)
+		if game == nil {
+			t.Fatalf("%s: FEN not parsed", c.name)
+		}
+		if result != c.want || game.Method() != c.method {
+			t.Errorf("%s: judged %q (%v), want %q (%v)", c.name, result, game.Method(), c.want, c.method)
+		}
+	}
+
+	// 클라이언트가 이겼다고 보내도 K 대 K면 무승부 보상을 받습니다.
+	if reward, method := terminalReward("White", "8/8/8/4k3/8/8/8/4K3 w - - 0 60", chess.White); reward != ai.DrawReward || method != chess.InsufficientMaterial {
+		t.Errorf("reward %v (%v), want draw reward %v", reward, method, ai.DrawReward)
+	}
+}
+
+func TestBlendWeightsChangeSelectedMove(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	// Q테이블은 a2a3을 높게 보지만 평가로는 다른 수가 낫습니다.
+	pos := chess.StartingPosition()
+	qrow := map[string]float64{"a2a3": ai.WinReward}
+	best := func(qWeight, evalWeight float64) string {
+		ai.QWeight, ai.EvalWeight = qWeight, evalWeight
+		return rankMoves(pos, pos.ValidMoves(), qrow, make(transTable), nil)[0].move.String()
+	}
+	if got := best(0, 1); got == "a2a3" {
+		t.Errorf("eval only: picked %s, want an evaluation move", got)
+	}
+	if got := best(1, 0); got != "a2a3" {
+		t.Errorf("Q only: picked %s, want a2a3", got)
+	}
+	if got := best(1, 1); got != "a2a3" {
+		t.Errorf("equal weights: picked %s, want the strongly learned a2a3", got)
+	}
+	if got := best(0.01, 1); got == "a2a3" {
+		t.Errorf("mostly eval: picked %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	configHandler(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg["q_weight"] != 0.01 || cfg["eval_weight"] != 1.0 {
+		t.Errorf("/config q_weight=%v eval_weight=%v, want 0.01 and 1", cfg["q_weight"], cfg["eval_weight"])
+	}
+}
+
+func TestQStaysBoundedAfterManyWins(t *testing.T) {
+	newTestAI(t)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment, ai.Lambda, ai.NStep = false, false, false, 0, 1
+	tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	visits := make(map[string]map[string]int)
+	history := []string{"s0|m0|0|s1", "s1|m1|0|"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		learnInto(tables, visits, history, ai.WinReward, ai.Alpha, r)
+	}
+	// 학습률로 목표값 쪽으로 옮기므로 승리를 아무리 쌓아도 Q는 종료 보상 근처에 머뭅니다.
+	if q := tables.a["s1"]["m1"]; math.Abs(q-ai.WinReward) > 1e-6 {
+		t.Errorf("Q(s1,m1) after 5000 wins = %v, want it to converge to %v", q, ai.WinReward)
+	}
+	for _, row := range tables.a {
+		for move, q := range row {
+			if q > ai.WinReward*(1+ai.Gamma) {
+				t.Errorf("Q(%s) = %v grew past the reward scale", move, q)
+			}
+		}
+	}
+
+	// 고를 때는 ±q_scale 안으로 눌러 평가와 섞습니다. 승리 수준이면 q_scale의 약 76%입니다.
+	if got := normalizeQ(ai.WinReward); math.Abs(got-ai.QScale*math.Tanh(1)) > 1e-9 {
+		t.Errorf("normalizeQ(win) = %v, want %v", got, ai.QScale*math.Tanh(1))
+	}
+	if got := normalizeQ(1000 * ai.WinReward); got > ai.QScale {
+		t.Errorf("normalizeQ(1000 wins) = %v, want at most q_scale %v", got, ai.QScale)
+	}
+	if got := normalizeQ(-1000 * ai.WinReward); got < -ai.QScale {
+		t.Errorf("normalizeQ(-1000 wins) = %v, want at least -q_scale", got)
+	}
+}
+
+func TestLearningOffLeavesBrainUnchanged(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	start := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	move := func(body map[string]interface{}) {
+		t.Helper()
+		if rec := postJSON(t, moveHandler, "/move", body); rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	// 요청의 learn이 false면 Q테이블과 학습 기록을 건드리지 않습니다.
+	move(map[string]interface{}{"fen": start, "color": "black", "session_id": "off", "learn": false})
+	if len(ai.QTable) != 0 || len(ai.sessions["off"].history) != 0 {
+		t.Fatalf("learn=false changed the brain: q=%v history=%v", ai.QTable, ai.sessions["off"].history)
+	}
+	move(map[string]interface{}{"fen": start, "color": "black", "session_id": "off", "result": "White", "learn": false})
+	if len(ai.QTable) != 0 || ai.GameCount != 0 {
+		t.Errorf("learn=false result: q=%v game_count=%d", ai.QTable, ai.GameCount)
+	}
+
+	// 요청에 learn이 없으면 learning_enabled 설정을 따릅니다.
+	ai.LearningEnabled = false
+	move(map[string]interface{}{"fen": start, "color": "black", "session_id": "default"})
+	if len(ai.QTable) != 0 {
+		t.Errorf("learning_enabled=false: q=%v", ai.QTable)
+	}
+	ai.LearningEnabled = true
+	move(map[string]interface{}{"fen": start, "color": "black", "session_id": "on"})
+	if len(ai.QTable) != 1 || len(ai.sessions["on"].history) != 1 {
+		t.Errorf("learning on: q=%v history=%v", ai.QTable, ai.sessions["on"].history)
+	}
+
+	rec := httptest.NewRecorder()
+	configHandler(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if !strings.Contains(rec.Body.String(), `"learning_enabled":true`) {
+		t.Errorf("/config does not show learning_enabled: %s", rec.Body)
+	}
+}
+
+func TestCheckGetsSlightlyHigherShapedReward(t *testing.T) {
+	newTestAI(t)
+	// Re2+와 Rd2는 룩 위치만 다릅니다. 체크 가점이 없으면 두 수의 중간 보상 차이는 기동력 등 다른 항목뿐입니다.
+	pos := mustPos(t, "4k3/8/8/8/8/8/R7/4K3 w - - 0 1")
+	check, quiet := mustMove(t, pos, "a2e2"), mustMove(t, pos, "a2d2")
+	ai.CheckBonus = 0
+	baseCheck, baseQuiet := shapedReward(pos, check), shapedReward(pos, quiet)
+	ai.CheckBonus = 2
+	withCheck, withQuiet := shapedReward(pos, check), shapedReward(pos, quiet)
+
+	if withQuiet != baseQuiet {
+		t.Errorf("quiet move reward changed with check_bonus: %v -> %v", baseQuiet, withQuiet)
+	}
+	if diff, want := withCheck-baseCheck, ai.ShapingWeight*ai.CheckBonus; math.Abs(diff-want) > 1e-9 {
+		t.Errorf("check adds %v to the shaped reward, want shaping_weight*check_bonus = %v", diff, want)
+	}
+	if withCheck-withQuiet <= baseCheck-baseQuiet {
+		t.Errorf("check vs quiet: %v vs %v, want the check ahead by more than without the bonus", withCheck, withQuiet)
+	}
+	// 가점은 작게 둡니다. 폰 하나(10)보다 훨씬 작습니다.
+	if withCheck-withQuiet >= ai.ShapingWeight*ai.PieceValues.Pawn {
+		t.Errorf("check bonus %v is not small next to a pawn", withCheck-withQuiet)
+	}
+
+	// 지키지 않은 더 비싼 기물을 공격하는 수는 위협 가점을 받습니다.
+	knight := mustPos(t, "4k3/8/8/3q4/8/8/4N3/4K3 w - - 0 1")
+	threat := knight.Update(mustMove(t, knight, "e2f4"))
+	if got := evaluateSides(threat)[chess.White].Threats; got != ai.ThreatBonus {
+		t.Errorf("threats after Nf4 = %v, want %v", got, ai.ThreatBonus)
+	}
+}
+
+func TestMoveConfidence(t *testing.T) {
+	if got := moveConfidence(0, 100); got != 0 {
+		t.Errorf("unseen state: confidence = %v, want 0", got)
+	}
+	if got := moveConfidence(1000, 0); got != 0 {
+		t.Errorf("tied candidates: confidence = %v, want 0", got)
+	}
+	if low, high := moveConfidence(2, 50), moveConfidence(200, 50); low >= high {
+		t.Errorf("confidence with 2 visits %v, with 200 visits %v, want it to grow", low, high)
+	}
+
+	// 공짜 퀸을 잡는 수는 다른 후보보다 확실히 좋으므로 학습량이 확신도를 정합니다.
+	newTestAI(t)
+	const fen = "4k3/8/8/3q4/8/8/8/3QK3 w - - 0 1"
+	confidence := func(session string) (string, float64) {
+		rec := postJSON(t, moveHandler, "/move", map[string]string{"fen": fen, "session_id": session})
+		var resp struct {
+			UCI        string
+			Confidence float64
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.UCI, resp.Confidence
+	}
+	if move, conf := confidence("unseen"); move != "d1d5" || conf > 0.1 {
+		t.Errorf("unseen state: move %s confidence %v, want d1d5 with low confidence", move, conf)
+	}
+	ai.Visits[stateKey(mustPos(t, fen))] = map[string]int{"d1d5": 1000}
+	if move, conf := confidence("trained"); move != "d1d5" || conf < 0.9 {
+		t.Errorf("trained state: move %s confidence %v, want d1d5 with high confidence", move, conf)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Q테이블을 합치는 방식
+const (
+	mergeSum     = "sum"     // 두 값을 더함
+	mergeAverage = "average" // 평균 (방문 횟수가 있으면 가중 평균)
+	mergeMax     = "max"     // 큰 값
+)
+
+func validMergeMode(mode string) bool {
+	return mode == mergeSum || mode == mergeAverage || mode == mergeMax
+}
+
+// src의 Q값을 dst에 합칩니다. 한쪽에만 있는 상태-수는 그대로 옮기고,
+// 양쪽에 있으면 mode에 따라 합칩니다. 방문 횟수를 모를 때 쓰며 평균은 단순 평균입니다.
+func mergeQTables(dst, src map[string]map[string]float64, mode string) {
+	mergeWeighted(dst, src, nil, nil, mode)
+}
+
+// mergeQTables와 같지만 average를 양쪽 방문 횟수로 가중합니다.
+// 두 쪽 다 방문 횟수가 없으면 단순 평균입니다. 방문 횟수 표는 바꾸지 않습니다.
+func mergeWeighted(dst, src map[string]map[string]float64, dstVisits, srcVisits map[string]map[string]int, mode string) {
+	for state, actions := range src {
+		if dst[state] == nil {
+			dst[state] = make(map[string]float64, len(actions))
+		}
+		for move, q := range actions {
+			old, ok := dst[state][move]
+			if !ok {
+				dst[state][move] = q
+				continue
+			}
+			switch mode {
+			case mergeSum:
+				dst[state][move] = old + q
+			case mergeMax:
+				if q > old {
+					dst[state][move] = q
+				}
+			default:
+				wd, ws := float64(dstVisits[state][move]), float64(srcVisits[state][move])
+				if wd+ws == 0 {
+					wd, ws = 1, 1
+				}
+				dst[state][move] = (old*wd + q*ws) / (wd + ws)
+			}
+		}
+	}
+}
+
+// 예전 형식(전체 FEN) 키를 정규화된 키로 합치고 빈 표를 채웁니다.
+func (b *brainFile) normalize() {
+	b.QTable = mergeStateKeys(b.QTable)
+	b.QB = mergeStateKeys(b.QB)
+	visits := make(map[string]map[string]int, len(b.Visits))
+	for fen, actions := range b.Visits {
+		key := normalizeFEN(fen)
+		if visits[key] == nil {
+			visits[key] = make(map[string]int)
+		}
+		for move, n := range actions {
+			visits[key][move] += n
+		}
+	}
+	b.Visits = visits
+}
+
+// src를 b에 합칩니다. Q값을 먼저 합친 뒤 방문 횟수와 대국 수를 더합니다.
+func (b *brainFile) merge(src brainFile, mode string) {
+	mergeWeighted(b.QTable, src.QTable, b.Visits, src.Visits, mode)
+	mergeWeighted(b.QB, src.QB, b.Visits, src.Visits, mode)
+	for state, actions := range src.Visits {
+		if b.Visits[state] == nil {
+			b.Visits[state] = make(map[string]int, len(actions))
+		}
+		for move, n := range actions {
+			b.Visits[state][move] += n
+		}
+	}
+	b.GameCount += src.GameCount
+}
+
+// Q테이블 파일(.gz 가능)을 읽어 키를 정규화합니다.
+func readBrain(path string) (brainFile, error) {
+	var brain brainFile
+	data, err := readQFile(path)
+	if err != nil {
+		return brain, err
+	}
+	if err := json.Unmarshal(data, &brain); err != nil {
+		return brain, fmt.Errorf("%s: %w", path, err)
+	}
+	brain.normalize()
+	return brain, nil
+}
+
+// -merge a.json b.json.gz ... -out merged.json
+// 여러 Q테이블 파일을 순서대로 합쳐 out에 씁니다. out이 .gz로 끝나면 압축합니다.
+func runMerge(files []string, out, mode string) error {
+	if !validMergeMode(mode) {
+		return fmt.Errorf("unknown merge mode %q (sum, average, max)", mode)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no Q-table files to merge")
+	}
+	merged := brainFile{}
+	merged.normalize()
+	for _, path := range files {
+		brain, err := readBrain(path)
+		if err != nil {
+			return err
+		}
+		merged.merge(brain, mode)
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(out, ".gz") {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	return writeFileAtomic(out, data)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeQTablesModes(t *testing.T) {
+	tests := []struct {
+		mode      string
+		overlap   float64
+		weighted  float64 // dst 3번, src 1번 방문했을 때
+		disjointA float64
+	}{
+		{mergeSum, 6, 6, 1},
+		{mergeAverage, 3, 2.5, 1},
+		{mergeMax, 4, 4, 1},
+	}
+	for _, tt := range tests {
+		dst := map[string]map[string]float64{"both": {"e2e4": 2}, "onlyDst": {"a2a3": 1}}
+		src := map[string]map[string]float64{"both": {"e2e4": 4}, "onlySrc": {"h2h3": 5}}
+		mergeQTables(dst, src, tt.mode)
+		if got := dst["both"]["e2e4"]; got != tt.overlap {
+			t.Errorf("%s: overlapping = %v, want %v", tt.mode, got, tt.overlap)
+		}
+		if dst["onlyDst"]["a2a3"] != tt.disjointA || dst["onlySrc"]["h2h3"] != 5 {
+			t.Errorf("%s: disjoint states = %v", tt.mode, dst)
+		}
+
+		dst = map[string]map[string]float64{"both": {"e2e4": 2}}
+		src = map[string]map[string]float64{"both": {"e2e4": 4}}
+		dv := map[string]map[string]int{"both": {"e2e4": 3}}
+		sv := map[string]map[string]int{"both": {"e2e4": 1}}
+		mergeWeighted(dst, src, dv, sv, tt.mode)
+		if got := dst["both"]["e2e4"]; got != tt.weighted {
+			t.Errorf("%s: visit-weighted = %v, want %v", tt.mode, got, tt.weighted)
+		}
+	}
+}
+
+func TestRunMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, b brainFile) string {
+		path := filepath.Join(dir, name)
+		data, _ := json.Marshal(b)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.json", brainFile{
+		QTable:    map[string]map[string]float64{"s": {"e2e4": 1}},
+		Visits:    map[string]map[string]int{"s": {"e2e4": 1}},
+		GameCount: 2,
+	})
+	b := write("b.json", brainFile{
+		QTable:    map[string]map[string]float64{"s": {"e2e4": 5}, "t": {"d2d4": 2}},
+		Visits:    map[string]map[string]int{"s": {"e2e4": 3}},
+		GameCount: 3,
+	})
+	out := filepath.Join(dir, "merged.json.gz")
+	if err := runMerge([]string{a, b}, out, mergeAverage); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := readBrain(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.QTable["s"]["e2e4"] != 4 || merged.QTable["t"]["d2d4"] != 2 {
+		t.Errorf("merged QTable = %v", merged.QTable)
+	}
+	if merged.Visits["s"]["e2e4"] != 4 || merged.GameCount != 5 {
+		t.Errorf("merged visits %v, game count %d", merged.Visits, merged.GameCount)
+	}
+	if err := runMerge([]string{a}, out, "median"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus 지표. GET /metrics로 내보냅니다. 게이지는 /stats와 같은 스냅숏(currentStats)을 읽습니다.
+var (
+	moveRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chessbot_move_requests_total",
+		Help: "Number of /move requests that asked the bot for a move.",
+	})
+	gamesCompleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chessbot_games_completed_total",
+		Help: "Number of games finished through /move and learned from.",
+	})
+	saves = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chessbot_saves_total",
+		Help: "Number of Q-table saves by result.",
+	}, []string{"result"})
+	moveLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chessbot_move_selection_seconds",
+		Help:    "Time spent choosing a move for /move.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "chessbot_qtable_states",
+		Help: "Number of states in the Q-table.",
+	}, func() float64 {
+		return float64(currentStats().States)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "chessbot_game_count",
+		Help: "Total number of games learned from (including self-play and PGN).",
+	}, func() float64 {
+		return float64(currentStats().GameCount)
+	})
+)
+
+// 저장 결과를 지표에 남깁니다.
+func recordSave(err error) {
+	if err != nil {
+		saves.WithLabelValues("failure").Inc()
+		return
+	}
+	saves.WithLabelValues("success").Inc()
+}
@@ -0,0 +1,65 @@
+package main
+
+import "strings"
+
+// 좌우 대칭(a↔h 파일)으로 뒤집은 파일 문자
+func mirrorFile(f byte) byte {
+	return 'a' + 'h' - f
+}
+
+// FEN(또는 정규화한 상태 키)을 좌우로 뒤집습니다.
+// 랭크마다 기물·빈칸 묶음의 순서만 뒤집으면 되고, 앙파상 칸도 파일을 뒤집습니다.
+// 캐슬링 권리는 대칭이 아니므로 뒤집지 않습니다. 대칭 학습에는 mirrorable을 먼저 확인하세요.
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return fen
+	}
+	ranks := strings.Split(fields[0], "/")
+	for i, rank := range ranks {
+		b := []byte(rank)
+		for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+			b[l], b[r] = b[r], b[l]
+		}
+		ranks[i] = string(b)
+	}
+	fields[0] = strings.Join(ranks, "/")
+	if len(fields) > 3 && len(fields[3]) == 2 {
+		ep := []byte(fields[3])
+		ep[0] = mirrorFile(ep[0])
+		fields[3] = string(ep)
+	}
+	return strings.Join(fields, " ")
+}
+
+// UCI 수를 좌우로 뒤집습니다. (예: e2e4 → d2d4, g7g8q → b7b8q)
+func mirrorMove(uci string) string {
+	if len(uci) < 4 {
+		return uci
+	}
+	b := []byte(uci)
+	b[0] = mirrorFile(b[0])
+	b[2] = mirrorFile(b[2])
+	return string(b)
+}
+
+// 캐슬링 권리가 남아 있으면 왕과 룩의 위치가 좌우 대칭이 아니므로 뒤집지 않습니다.
+func mirrorable(fen string) bool {
+	fields := strings.Fields(fen)
+	return len(fields) >= 3 && fields[2] == "-"
+}
+
+// [학습] 전이를 좌우로 뒤집습니다. 상태나 다음 상태 중 하나라도 뒤집을 수 없으면 ok가 false입니다.
+func mirrorTransition(t transition) (transition, bool) {
+	if !mirrorable(t.state) || (t.next != "" && !mirrorable(t.next)) {
+		return transition{}, false
+	}
+	m := transition{state: mirrorFEN(t.state), move: mirrorMove(t.move), reward: t.reward, steps: t.steps}
+	if t.next != "" {
+		m.next = mirrorFEN(t.next)
+	}
+	if t.nextMove != "" {
+		m.nextMove = mirrorMove(t.nextMove)
+	}
+	return m, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMirrorFEN(t *testing.T) {
+	cases := []struct{ fen, want string }{
+		{"4k3/8/8/8/8/8/4P3/3QK3 w - -", "3k4/8/8/8/8/8/3P4/3KQ3 w - -"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6", "3k4/8/8/3Pp3/8/8/8/3K4 w - e6"},
+	}
+	for _, c := range cases {
+		if got := mirrorFEN(c.fen); got != c.want {
+			t.Errorf("mirrorFEN(%q) = %q, want %q", c.fen, got, c.want)
+		}
+		if back := mirrorFEN(mirrorFEN(c.fen)); back != c.fen {
+			t.Errorf("mirroring twice gave %q, want %q", back, c.fen)
+		}
+	}
+	for uci, want := range map[string]string{"e2e4": "d2d4", "a7a8n": "h7h8n", "g1f3": "b1c3"} {
+		if got := mirrorMove(uci); got != want {
+			t.Errorf("mirrorMove(%s) = %s, want %s", uci, got, want)
+		}
+	}
+}
+
+// 학습한 뒤 실제로 좌우 반전한 포지션에서 상태 키를 만들면 반전한 수의 값을 찾습니다.
+func TestAugmentLearnsMirroredValue(t *testing.T) {
+	newTestAI(t)
+	ai.Augment, ai.DoubleQ, ai.AdaptiveAlpha, ai.Lambda, ai.NStep = true, false, false, 0, 1
+	pos := mustPos(t, "4k3/8/8/8/8/8/4P3/3QK3 w - - 0 1")
+	history := recordMove(nil, pos, mustMove(t, pos, "d1d7"))
+	tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	learnInto(tables, make(map[string]map[string]int), history, 1, 0.5, rand.New(rand.NewSource(1)))
+
+	learned := tables.a[stateKey(pos)]["d1d7"]
+	if learned == 0 {
+		t.Fatal("original move learned nothing")
+	}
+	mirrored := mustPos(t, "3k4/8/8/8/8/8/3P4/3KQ3 w - - 0 1")
+	if got := tables.a[stateKey(mirrored)]["e1e7"]; got != learned {
+		t.Errorf("mirrored value = %v, want %v", got, learned)
+	}
+}
+
+func TestAugmentSkipsCastlingRights(t *testing.T) {
+	newTestAI(t)
+	ai.Augment, ai.DoubleQ, ai.AdaptiveAlpha, ai.Lambda, ai.NStep = true, false, false, 0, 1
+	pos := mustPos(t, "4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1")
+	history := recordMove(nil, pos, mustMove(t, pos, "a1a7"))
+	tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	learnInto(tables, make(map[string]map[string]int), history, 1, 0.5, rand.New(rand.NewSource(1)))
+
+	if len(tables.a) != 1 {
+		t.Errorf("learned %d states, want only the original (castling rights are not symmetric)", len(tables.a))
+	}
+	if _, ok := mirrorTransition(transition{state: "4k3/8/8/8/8/8/8/R3K2R w KQ -", move: "a1a7"}); ok {
+		t.Error("mirrorTransition accepted a position with castling rights")
+	}
+	if !mirrorable("4k3/8/8/3pP3/8/8/8/4K3 w - d6") {
+		t.Error("en passant alone should stay mirrorable (the square is mirrored too)")
+	}
+}
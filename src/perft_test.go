@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// 널리 알려진 perft 값 (https://www.chessprogramming.org/Perft_Results)
+func TestPerft(t *testing.T) {
+	kiwipete := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	cases := []struct {
+		name string
+		pos  *chess.Position
+		want []uint64
+	}{
+		{"startpos", chess.NewGame().Position(), []uint64{20, 400, 8902}},
+		{"kiwipete", mustPos(t, kiwipete), []uint64{48, 2039, 97862}},
+	}
+	for _, c := range cases {
+		for i, want := range c.want {
+			if got := perft(c.pos, i+1); got != want {
+				t.Errorf("%s depth %d = %d, want %d", c.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestRunPerftDivide(t *testing.T) {
+	var out bytes.Buffer
+	if err := runPerft([]string{"startpos", "2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); !strings.Contains(s, "e2e4: 20\n") || !strings.HasSuffix(s, "Nodes searched: 400\n") {
+		t.Errorf("output = %q", s)
+	}
+	if err := runPerft([]string{"startpos", "0"}, &out); err == nil {
+		t.Error("depth 0 was accepted")
+	}
+}
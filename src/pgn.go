@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// 끝난 대국을 PGN으로 모아 두는 파일
+var pgnFile = "games.pgn"
+
+// 두 포지션의 기물 배치, 차례, 캐슬링 권리가 같은지 봅니다.
+// 클라이언트마다 앙파상 칸 표기가 달라 그 뒤 필드는 비교하지 않습니다.
+func samePosition(a, b *chess.Position) bool {
+	fa, fb := strings.Fields(a.String()), strings.Fields(b.String())
+	return strings.Join(fa[:3], " ") == strings.Join(fb[:3], " ")
+}
+
+// from에서 to로 가는 합법 수를 찾습니다. 없으면 nil입니다.
+func moveBetween(from, to *chess.Position) *chess.Move {
+	for _, m := range from.ValidMoves() {
+		if samePosition(from.Update(m), to) {
+			return m
+		}
+	}
+	return nil
+}
+
+// 기록을 시작할 게임을 만듭니다. 시작 포지션이거나 시작 포지션에서 한 수 둔
+// 포지션(AI가 흑일 때)이면 표준 시작부터 기록합니다.
+func newRecordedGame(pos *chess.Position) *chess.Game {
+	game := chess.NewGame()
+	if samePosition(game.Position(), pos) {
+		return game
+	}
+	if m := moveBetween(game.Position(), pos); m != nil && playMove(game, m) {
+		return game
+	}
+	opt, _ := chess.FEN(pos.String())
+	return chess.NewGame(opt)
+}
+
+// 세션의 실제 대국 기록을 pos에 맞춥니다. 세션이 없으면 만듭니다. ai.mu 잠금 아래에서 호출합니다.
+// 상대가 둔 수는 이전 포지션에서 pos로 가는 합법 수를 찾아 채우고,
+// 기록이 없거나 이어지지 않거나 maxSessionPlies를 넘었으면 pos에서 새로 시작합니다.
+func syncSessionGame(sessionID string, pos *chess.Position) *chess.Game {
+	s := getSession(sessionID)
+	game := s.game
+	if game != nil && len(game.Moves()) < maxSessionPlies {
+		if samePosition(game.Position(), pos) {
+			return game
+		}
+		if m := moveBetween(game.Position(), pos); m != nil && playMove(game, m) {
+			return game
+		}
+	}
+	game = newRecordedGame(pos)
+	s.game = game
+	return game
+}
+
+// 세션의 대국을 마무리해 PGN 문자열로 만듭니다. 기록이 없으면 빈 문자열입니다.
+// ai.mu 잠금 아래에서 호출합니다.
+func sessionPGN(sessionID, result, fen string, aiColor chess.Color, gameNo int) string {
+	s := ai.sessions[sessionID]
+	if s == nil || s.game == nil {
+		return ""
+	}
+	game := s.game
+	// 게임을 끝낸 상대의 마지막 수를 채웁니다.
+	if opt, err := chess.FEN(fen); err == nil {
+		if m := moveBetween(game.Position(), chess.NewGame(opt).Position()); m != nil {
+			playMove(game, m)
+		}
+	}
+	if game.Outcome() == chess.NoOutcome {
+		switch result {
+		case chess.White.Name():
+			game.Resign(chess.Black)
+		case chess.Black.Name():
+			game.Resign(chess.White)
+		default:
+			game.Draw(chess.DrawOffer)
+		}
+	}
+
+	players := map[chess.Color]string{aiColor: "RL Chess Bot", aiColor.Other(): "Human"}
+	game.AddTagPair("Event", "RL Chess Bot")
+	game.AddTagPair("Date", time.Now().Format("2006.01.02"))
+	game.AddTagPair("Round", strconv.Itoa(gameNo))
+	game.AddTagPair("White", players[chess.White])
+	game.AddTagPair("Black", players[chess.Black])
+	game.AddTagPair("Result", game.Outcome().String())
+	if start := game.Positions()[0]; !samePosition(start, chess.StartingPosition()) {
+		game.AddTagPair("SetUp", "1")
+		game.AddTagPair("FEN", start.String())
+	}
+	return game.String()
+}
+
+// PGN 한 판을 games.pgn 끝에 덧붙입니다.
+func appendPGN(pgn string) error {
+	f, err := os.OpenFile(pgnFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s\n\n", strings.TrimSpace(pgn)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// GET /games: 지금까지 끝난 대국의 PGN 모음을 내려받습니다.
+func gamesHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(pgnFile)
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", `attachment; filename="games.pgn"`)
+	w.Write(data)
+}
+
+// PGN 학습 요청 본문의 최대 크기
+const maxPGNUpload = 64 << 20
+
+// 여러 판이 들어 있는 PGN 텍스트를 판 단위로 나눕니다.
+// 수 목록 뒤에 태그가 다시 나오면 새 판이 시작된 것으로 봅니다.
+func splitPGN(text string) []string {
+	var games []string
+	var sb strings.Builder
+	inMoves := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		isTag := strings.HasPrefix(trimmed, "[")
+		if isTag && inMoves {
+			games = append(games, sb.String())
+			sb.Reset()
+			inMoves = false
+		}
+		if trimmed != "" && !isTag {
+			inMoves = true
+		}
+		sb.WriteString(line + "\n")
+	}
+	if strings.TrimSpace(sb.String()) != "" {
+		games = append(games, sb.String())
+	}
+	return games
+}
+
+// [학습] 기보 한 판을 처음부터 다시 두면서 양쪽의 수를 기록하고,
+// 게임 결과를 보상으로 삼아 /move와 같은 learn으로 학습합니다.
+// 결과가 없는 판("*")은 학습하지 않고 false를 반환합니다.
+func trainFromGame(game *chess.Game) bool {
+	result := "Draw"
+	switch game.Outcome() {
+	case chess.WhiteWon:
+		result = chess.White.Name()
+	case chess.BlackWon:
+		result = chess.Black.Name()
+	case chess.NoOutcome:
+		return false
+	}
+
+	// 수 기록의 보상(shapedReward)은 평가 설정을 읽으므로 configMu 읽기 잠금 아래에서 만듭니다.
+	configMu.RLock()
+	defer configMu.RUnlock()
+	histories := make(map[chess.Color][]string)
+	positions := game.Positions()
+	for i, m := range game.Moves() {
+		pos := positions[i]
+		histories[pos.Turn()] = recordMove(histories[pos.Turn()], pos, m)
+	}
+	fen := game.Position().String()
+
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	for color, history := range histories {
+		reward, _ := terminalReward(result, fen, color)
+		learn(history, reward)
+	}
+	ai.GameCount++
+	recordProgress()
+	return true
+}
+
+// POST /train/pgn: 본문의 PGN(여러 판 가능)으로 Q테이블을 학습합니다.
+// 읽을 수 없는 판은 건너뛰고 개수만 알려 줍니다.
+func trainPGNHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPGNUpload))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read PGN: "+err.Error())
+		return
+	}
+
+	ingested, skipped := 0, 0
+	for _, text := range splitPGN(string(body)) {
+		opt, err := chess.PGN(strings.NewReader(text))
+		if err != nil || !trainFromGame(chess.NewGame(opt)) {
+			skipped++
+			continue
+		}
+		ingested++
+	}
+	if ingested > 0 {
+		saveToFile()
+	}
+	writeJSON(w, http.StatusOK, map[string]int{
+		"ingested": ingested,
+		"skipped":  skipped,
+	})
+}
+
+// GET /replay?game=N&ply=M
+// 기보 모음(games.pgn)의 N번째 판(1부터)을 M반수(0이면 시작 포지션)까지 둔 포지션과
+// 그 포지션에서 둔 수, 평가값(백 관점)을 돌려줍니다. 리뷰 화면에서 한 수씩 넘겨 보며 평가 곡선을 그릴 때 씁니다.
+// 판이나 반수가 범위를 벗어나면 404입니다. 학습하지 않습니다.
+func replayHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	n, err := strconv.Atoi(r.URL.Query().Get("game"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "game must be a number")
+		return
+	}
+	ply, err := strconv.Atoi(r.URL.Query().Get("ply"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "ply must be a number")
+		return
+	}
+	data, err := os.ReadFile(pgnFile)
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	games := splitPGN(string(data))
+	if n < 1 || n > len(games) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("game %d not found (%d games)", n, len(games)))
+		return
+	}
+	opt, err := chess.PGN(strings.NewReader(games[n-1]))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("game %d: %v", n, err))
+		return
+	}
+	game := chess.NewGame(opt)
+	moves, positions := game.Moves(), game.Positions()
+	if ply < 0 || ply > len(moves) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("ply %d out of range (0-%d)", ply, len(moves)))
+		return
+	}
+
+	pos := positions[ply]
+	ai.mu.RLock()
+	eval := evaluateBoard(pos, chess.White)
+	ai.mu.RUnlock()
+	resp := map[string]interface{}{
+		"game":   n,
+		"games":  len(games),
+		"ply":    ply,
+		"plies":  len(moves),
+		"fen":    pos.String(),
+		"eval":   eval,
+		"result": game.Outcome().String(),
+	}
+	for _, tag := range game.TagPairs() {
+		if tag.Key == "White" || tag.Key == "Black" {
+			resp[strings.ToLower(tag.Key)] = tag.Value
+		}
+	}
+	// 마지막 포지션에서는 둔 수가 없습니다.
+	if ply < len(moves) {
+		resp["move"] = moves[ply].String()
+		resp["san"] = chess.AlgebraicNotation{}.Encode(pos, moves[ply])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
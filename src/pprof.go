@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// -pprof일 때 /debug/pprof/ 아래에 프로파일링 핸들러를 등록합니다.
+// net/http/pprof는 가져오기만 해도 http.DefaultServeMux에 등록하므로 서버는 따로 만든 mux를 씁니다.
+// 내부 상태가 드러나므로 admin_token이 있으면 관리용 엔드포인트처럼 토큰을 요구합니다.
+//
+//	go tool pprof http://localhost:8080/debug/pprof/profile?seconds=30
+func registerPprof(mux *http.ServeMux, token string) {
+	mux.HandleFunc("/debug/pprof/", requireAdmin(token, pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", requireAdmin(token, pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", requireAdmin(token, pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", requireAdmin(token, pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", requireAdmin(token, pprof.Trace))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// 학습 곡선으로 남겨 두는 기록 수. 넘으면 오래된 것부터 버립니다.
+const maxProgress = 500
+
+// 학습 진행 기록 한 점
+type progressPoint struct {
+	Time      time.Time `json:"time"`
+	GameCount int       `json:"game_count"`
+	WinRate   float64   `json:"win_rate"`     // 그 시점의 최근 100판 승률 (/stats의 recent와 같음)
+	Games     int       `json:"recent_games"` // win_rate를 계산한 판 수
+	BrainSize int       `json:"brain_size"`
+	AvgQ      float64   `json:"avg_q"`
+}
+
+// GameCount가 progress_every의 배수가 될 때마다 학습 현황을 ai.Progress에 덧붙입니다.
+// 같은 판 수에서 두 번 기록하지 않습니다. ai.mu 쓰기 잠금 아래에서 호출합니다.
+func recordProgress() {
+	if ai.ProgressEvery <= 0 || ai.GameCount == 0 || ai.GameCount%ai.ProgressEvery != 0 {
+		return
+	}
+	if n := len(ai.Progress); n > 0 && ai.Progress[n-1].GameCount == ai.GameCount {
+		return
+	}
+	entries, sum := 0, 0.0
+	for _, actions := range ai.QTable {
+		for _, q := range actions {
+			entries++
+			sum += q
+		}
+	}
+	recent := recentResults(ai.Results, defaultStatsWindow)
+	p := progressPoint{
+		Time:      time.Now(),
+		GameCount: ai.GameCount,
+		WinRate:   recent.WinRate,
+		Games:     recent.Games,
+		BrainSize: len(ai.QTable),
+	}
+	if entries > 0 {
+		p.AvgQ = sum / float64(entries)
+	}
+	ai.Progress = append(ai.Progress, p)
+	if n := len(ai.Progress); n > maxProgress {
+		ai.Progress = append([]progressPoint(nil), ai.Progress[n-maxProgress:]...)
+	}
+}
+
+// GET /progress
+// progress_every판마다 남긴 학습 현황을 오래된 것부터 돌려줍니다. (최대 500개, Q테이블과 함께 저장)
+func progressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	ai.mu.RLock()
+	points := append([]progressPoint{}, ai.Progress...)
+	every := ai.ProgressEvery
+	ai.mu.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"every":  every,
+		"points": points,
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// 상태 수가 max_states를 넘으면 이 비율까지 줄입니다.
+// 한 번에 여유분을 비워 두어 지울 상태를 고르는 비용이 학습마다 들지 않게 합니다.
+const evictTarget = 0.9
+
+// 한 상태에서 모든 수를 학습한 횟수의 합입니다.
+func stateVisits(state string) int {
+	total := 0
+	for _, n := range ai.Visits[state] {
+		total += n
+	}
+	return total
+}
+
+type stateCount struct {
+	state  string
+	visits int
+}
+
+// 방문 횟수가 가장 많은 것이 맨 위에 오는 힙. 지울 후보(가장 적게 방문한 k개)를 모읍니다.
+type evictHeap []stateCount
+
+func (h evictHeap) Len() int            { return len(h) }
+func (h evictHeap) Less(i, j int) bool  { return h[i].visits > h[j].visits }
+func (h evictHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *evictHeap) Push(x interface{}) { *h = append(*h, x.(stateCount)) }
+func (h *evictHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// [메모리] Q테이블 상태 수가 max_states를 넘으면 가장 적게 방문한 상태부터 지웁니다.
+// 넘을 때마다 상한의 evictTarget 비율까지 한꺼번에 줄이므로 후보 고르기는 가끔만 일어납니다.
+// 전체를 정렬하지 않고 지울 k개만 힙에 모으므로 상태 n개에 O(n log k)입니다.
+// ai.mu 쓰기 잠금 아래에서 호출합니다. 지운 상태 수를 반환합니다.
+func evictStates() int {
+	if ai.MaxStates <= 0 || len(ai.QTable) <= ai.MaxStates {
+		return 0
+	}
+	k := len(ai.QTable) - int(float64(ai.MaxStates)*evictTarget)
+
+	h := make(evictHeap, 0, k)
+	for state := range ai.QTable {
+		c := stateCount{state, stateVisits(state)}
+		switch {
+		case len(h) < k:
+			heap.Push(&h, c)
+		case c.visits < h[0].visits:
+			h[0] = c
+			heap.Fix(&h, 0)
+		}
+	}
+
+	for _, c := range h {
+		delete(ai.QTable, c.state)
+		delete(ai.QB, c.state)
+		delete(ai.Visits, c.state)
+	}
+	needSnapshot()
+	return len(h)
+}
+
+// POST /prune {"min_visits": N, "min_abs_qvalue": X}
+// 방문 횟수가 min_visits 미만이거나 |Q|가 min_abs_qvalue 미만인 상태-수 항목을 지우고,
+// 수가 하나도 남지 않은 상태도 지웁니다. 0인 기준은 쓰지 않습니다.
+func pruneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		MinVisits    int     `json:"min_visits"`
+		MinAbsQValue float64 `json:"min_abs_qvalue"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.MinVisits <= 0 && req.MinAbsQValue <= 0 {
+		writeError(w, http.StatusBadRequest, "min_visits or min_abs_qvalue must be positive")
+		return
+	}
+
+	ai.mu.Lock()
+	removed := 0
+	for state, actions := range ai.QTable {
+		for move, q := range actions {
+			if ai.DoubleQ {
+				q += ai.QB[state][move]
+			}
+			if (req.MinVisits > 0 && ai.Visits[state][move] < req.MinVisits) ||
+				(req.MinAbsQValue > 0 && math.Abs(q) < req.MinAbsQValue) {
+				delete(actions, move)
+				delete(ai.QB[state], move)
+				delete(ai.Visits[state], move)
+				removed++
+			}
+		}
+		if len(actions) == 0 {
+			delete(ai.QTable, state)
+			delete(ai.QB, state)
+			delete(ai.Visits, state)
+		}
+	}
+	brainSize := len(ai.QTable)
+	needSnapshot()
+	ai.mu.Unlock()
+	saveToFile()
+
+	writeJSON(w, http.StatusOK, map[string]int{
+		"removed":    removed,
+		"brain_size": brainSize,
+	})
+}
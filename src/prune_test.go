@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvictStatesKeepsMostVisited(t *testing.T) {
+	newTestAI(t)
+	ai.MaxStates = 100
+	for i := 0; i < 150; i++ {
+		state := fmt.Sprintf("s%d", i)
+		ai.QTable[state] = map[string]float64{"e2e4": 1}
+		ai.Visits[state] = map[string]int{"e2e4": i}
+	}
+	if n := evictStates(); n != 60 {
+		t.Errorf("evicted %d, want 60 (down to 90%% of the cap)", n)
+	}
+	if len(ai.QTable) != 90 {
+		t.Fatalf("%d states left, want 90", len(ai.QTable))
+	}
+	for state := range ai.QTable {
+		if v := stateVisits(state); v < 60 {
+			t.Errorf("kept %s with %d visits, a less visited state than those evicted", state, v)
+		}
+	}
+	if len(ai.Visits) != 90 {
+		t.Errorf("%d visit rows left, want 90", len(ai.Visits))
+	}
+}
+
+func TestEvictStatesStaysBounded(t *testing.T) {
+	newTestAI(t)
+	ai.MaxStates = 50
+	for i := 0; i < 1000; i++ {
+		state := fmt.Sprintf("s%d", i)
+		ai.QTable[state] = map[string]float64{"e2e4": 1}
+		ai.Visits[state] = map[string]int{"e2e4": i % 7}
+		evictStates()
+		if len(ai.QTable) > ai.MaxStates {
+			t.Fatalf("after %d inserts: %d states over the cap %d", i+1, len(ai.QTable), ai.MaxStates)
+		}
+	}
+	// 상한 아래면 아무것도 지우지 않습니다.
+	if n := evictStates(); n != 0 {
+		t.Errorf("evicted %d under the cap", n)
+	}
+}
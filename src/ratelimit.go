@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 이보다 많은 클라이언트를 기억하게 되면 다 찬(한동안 요청이 없던) 버킷을 버립니다.
+const limiterMaxClients = 10000
+
+// 클라이언트(IP)마다 하나씩 두는 토큰 버킷
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// IP별 토큰 버킷 요청 제한. 초당 rate개씩 burst개까지 토큰이 차고 요청마다 하나를 씁니다.
+type rateLimiter struct {
+	rate       float64
+	burst      float64
+	trustProxy bool // X-Forwarded-For의 첫 주소를 클라이언트로 볼지 (프록시 뒤에서만 켬)
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// rate가 0 이하면 제한하지 않는 nil을 돌려줍니다.
+func newRateLimiter(rate float64, burst int, trustProxy bool) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:       rate,
+		burst:      math.Max(float64(burst), 1),
+		trustProxy: trustProxy,
+		buckets:    make(map[string]*bucket),
+		now:        time.Now,
+	}
+}
+
+// key의 토큰을 하나 씁니다. 남은 토큰이 없으면 false와 다음 토큰까지 기다릴 시간을 돌려줍니다.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b := l.buckets[key]
+	if b == nil {
+		if len(l.buckets) >= limiterMaxClients {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// 지금쯤 다시 가득 찼을 버킷을 버립니다. 버려도 다음 요청에서 가득 찬 채로 새로 만들므로 결과가 같습니다.
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// next 앞에서 클라이언트 IP마다 요청 수를 제한합니다. 넘으면 429와 Retry-After를 돌려줍니다.
+// l이 nil이면 next를 그대로 돌려줍니다.
+func (l *rateLimiter) wrap(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.allow(clientIP(r, l.trustProxy))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// 요청을 보낸 클라이언트의 IP입니다. trustProxy면 X-Forwarded-For의 첫 주소(원래 클라이언트)를 씁니다.
+// 프록시 없이 X-Forwarded-For를 믿으면 클라이언트가 헤더를 바꿔 가며 제한을 피할 수 있습니다.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitReturns429(t *testing.T) {
+	l := newRateLimiter(1, 2, false)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+	h := l.wrap(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	get := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/move", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	// burst 2개까지는 통과하고 세 번째는 429입니다.
+	for i := 0; i < 2; i++ {
+		if rec := get("10.0.0.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := get("10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	// 다른 IP는 따로 셉니다.
+	if rec := get("10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("other client: status %d, want 200", rec.Code)
+	}
+	// 1초 뒤에는 토큰이 하나 찹니다.
+	now = now.Add(time.Second)
+	if rec := get("10.0.0.1:5678"); rec.Code != http.StatusOK {
+		t.Errorf("after refill: status %d, want 200", rec.Code)
+	}
+	if rec := get("10.0.0.1:5678"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("after refill, second request: status %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	if l := newRateLimiter(0, 10, false); l != nil {
+		t.Fatalf("rate 0 gave a limiter %+v", l)
+	}
+	var l *rateLimiter
+	called := false
+	l.wrap(func(http.ResponseWriter, *http.Request) { called = true })(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Error("nil limiter did not pass the request through")
+	}
+}
+
+func TestClientIPTrustsProxyOnlyWhenAsked(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 192.0.2.1")
+	if got := clientIP(req, false); got != "192.0.2.1" {
+		t.Errorf("untrusted = %q, want the remote address", got)
+	}
+	if got := clientIP(req, true); got != "203.0.113.7" {
+		t.Errorf("trusted = %q, want the first forwarded address", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// 보드를 랭크·파일 표시와 둘 차례를 붙인 글자 그림으로 그립니다.
+// unicode면 ♔♛ 같은 기물 문자를, 아니면 FEN 문자(백은 대문자)를 씁니다.
+func renderBoard(pos *chess.Position, unicode bool) string {
+	var sb strings.Builder
+	board := pos.Board()
+	sb.WriteString("  a b c d e f g h\n")
+	for r := 7; r >= 0; r-- {
+		sb.WriteString(chess.Rank(r).String())
+		for f := 0; f < 8; f++ {
+			p := board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+			sb.WriteString(" ")
+			switch {
+			case p == chess.NoPiece:
+				sb.WriteString(".")
+			case unicode:
+				sb.WriteString(p.String())
+			case p.Color() == chess.White:
+				sb.WriteString(strings.ToUpper(p.Type().String()))
+			default:
+				sb.WriteString(p.Type().String())
+			}
+		}
+		sb.WriteString(" " + chess.Rank(r).String() + "\n")
+	}
+	sb.WriteString("  a b c d e f g h\n")
+	sb.WriteString(pos.Turn().Name() + " to move\n")
+	return sb.String()
+}
+
+// POST /render {"fen": ..., "format": "ascii"|"unicode"}: 보드 그림을 텍스트로 돌려줍니다.
+func renderHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FEN    string `json:"fen"`
+		Format string `json:"format"` // 기본값 "ascii"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Format != "" && req.Format != "ascii" && req.Format != "unicode" {
+		writeError(w, http.StatusBadRequest, `format must be "ascii" or "unicode"`)
+		return
+	}
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(renderBoard(game.Position(), req.Format == "unicode")))
+}
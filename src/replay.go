@@ -0,0 +1,62 @@
+package main
+
+import "math/rand"
+
+// 학습 단위 하나: 상태 s에서 수 a를 두고 보상 r을 받아 s'로 갔다는 기록입니다.
+// next가 비어 있으면 게임이 끝난 수입니다.
+type transition struct {
+	state, move, next string
+	nextMove          string  // 다음 상태에서 실제로 둔 수 (SARSA용, 모르면 빈 문자열)
+	reward            float64 // next까지 받은 할인된 보상의 합
+	steps             int     // next가 몇 수 뒤 상태인지 (n단계 갱신, 0이면 1)
+}
+
+// 지난 대국의 전이를 담아 두는 고정 크기 링 버퍼입니다. ai.mu 잠금 아래에서 사용합니다.
+type replayBuffer struct {
+	items []transition
+	next  int // 가득 찼을 때 다음에 덮어쓸 위치
+}
+
+// 전이들을 넣습니다. capacity를 넘으면 가장 오래된 것부터 덮어씁니다.
+func (b *replayBuffer) add(ts []transition, capacity int) {
+	if capacity <= 0 {
+		return
+	}
+	if len(b.items) > capacity {
+		b.items, b.next = b.items[len(b.items)-capacity:], 0
+	}
+	for _, t := range ts {
+		if len(b.items) < capacity {
+			b.items = append(b.items, t)
+			continue
+		}
+		b.items[b.next] = t
+		b.next = (b.next + 1) % capacity
+	}
+}
+
+// 버퍼에서 n개를 무작위로(중복 허용) 뽑습니다.
+func (b *replayBuffer) sample(r *rand.Rand, n int) []transition {
+	if len(b.items) == 0 {
+		return nil
+	}
+	batch := make([]transition, n)
+	for i := range batch {
+		batch[i] = b.items[r.Intn(len(b.items))]
+	}
+	return batch
+}
+
+// [학습] 방금 학습한 전이를 버퍼에 넣고, 버퍼에서 뽑은 지난 전이로 Q값을 한 번 더 갱신합니다.
+// 마지막 판만 배우면 예전 경험을 잊고 갱신이 한 판에 몰리므로 이를 완화합니다.
+// 다시 보는 전이는 방문 횟수를 늘리지 않습니다. ai.mu 쓰기 잠금 아래에서 호출합니다.
+func replayExperience(ts []transition) {
+	ai.replay.add(ts, ai.ReplaySize)
+	if ai.ReplayBatch <= 0 {
+		return
+	}
+	alpha := ai.effectiveAlpha(ai.GameCount)
+	for _, t := range ai.replay.sample(ai.rng, ai.ReplayBatch) {
+		updateQ(sharedTables(), t, learningRate(ai.Visits[t.state][t.move], alpha), ai.rng)
+	}
+}
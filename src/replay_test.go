@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestReplayBufferWrapsAround(t *testing.T) {
+	var b replayBuffer
+	for i := 0; i < 5; i++ {
+		b.add([]transition{{state: fmt.Sprint(i)}}, 3)
+	}
+	// 가장 오래된 0, 1을 덮어쓰고 2, 3, 4가 남습니다.
+	got := map[string]bool{}
+	for _, tr := range b.items {
+		got[tr.state] = true
+	}
+	if len(b.items) != 3 || !got["2"] || !got["3"] || !got["4"] {
+		t.Errorf("buffer = %+v, want states 2, 3, 4", b.items)
+	}
+}
+
+func TestReplayRevisitsOldTransitions(t *testing.T) {
+	newTestAI(t)
+	ai.ReplaySize, ai.ReplayBatch, ai.DoubleQ = 100, 8, false
+	old := transition{state: "old", move: "e2e4", reward: 10}
+	replayExperience([]transition{old})
+	ai.QTable["old"]["e2e4"] = 0 // 보상(10)까지 다시 배웠는지 보려고 지웁니다
+	before := ai.QTable["old"]["e2e4"]
+
+	// 새 판을 여러 번 배워도 예전 판의 전이를 다시 뽑아 갱신합니다.
+	for i := 0; i < 5; i++ {
+		replayExperience([]transition{{state: fmt.Sprint("new", i), move: "d2d4", reward: -10}})
+	}
+	if after := ai.QTable["old"]["e2e4"]; after <= before {
+		t.Errorf("old transition not revisited: Q %v -> %v", before, after)
+	}
+	if ai.Visits["old"]["e2e4"] != 0 {
+		t.Errorf("replay counted visits: %v", ai.Visits["old"])
+	}
+}
+
+func TestReplaySampleIsSeeded(t *testing.T) {
+	var b replayBuffer
+	for i := 0; i < 50; i++ {
+		b.add([]transition{{state: fmt.Sprint(i)}}, 50)
+	}
+	x := b.sample(rand.New(rand.NewSource(9)), 10)
+	y := b.sample(rand.New(rand.NewSource(9)), 10)
+	for i := range x {
+		if x[i] != y[i] {
+			t.Fatalf("same seed sampled %v then %v", x, y)
+		}
+	}
+}
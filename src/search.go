@@ -0,0 +1,395 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// 체크메이트 점수. 어떤 기물 점수 합보다도 커야 합니다.
+// 실제 점수는 메이트까지의 거리만큼 조정해 빠른 메이트를 더 높게 봅니다.
+// 고정 깊이 탐색과 정지 탐색 모두 루트(수를 고르는 포지션)에서 p반수 뒤에 당한 메이트를 -(mateScore-p)로 매기므로,
+// 루트에서 보면 n수 메이트(2n-1반수)는 mateScore-(2n-1)입니다. 치환표에는 그 노드에서 잰 거리로 바꿔 넣습니다.
+const mateScore = 100000.0
+
+// 루트에서 메이트까지 셀 수 있는 최대 반수. 반복 심화 최대 깊이(maxIterDepth)와 정지 탐색 깊이를 더한 것보다 큽니다.
+const maxMatePly = 64
+
+// 점수가 메이트(이기든 지든)를 뜻하는지 봅니다.
+func isMateScore(score float64) bool {
+	return math.Abs(score) >= mateScore-maxMatePly
+}
+
+// 메이트 점수를 치환표용(그 노드에서 잰 거리)으로 바꿉니다. 다른 반수에서 같은 포지션을 만나도 거리가 맞습니다.
+func mateToTT(score float64, ply int) float64 {
+	switch {
+	case score >= mateScore-maxMatePly:
+		return score + float64(ply)
+	case score <= -(mateScore - maxMatePly):
+		return score - float64(ply)
+	}
+	return score
+}
+
+// mateToTT로 넣은 점수를 루트에서 잰 거리로 되돌립니다.
+func mateFromTT(score float64, ply int) float64 {
+	switch {
+	case score >= mateScore-maxMatePly:
+		return score - float64(ply)
+	case score <= -(mateScore - maxMatePly):
+		return score + float64(ply)
+	}
+	return score
+}
+
+// 치환표 항목의 점수 종류
+const (
+	ttExact = iota // 정확한 값
+	ttLower        // 하한 (베타 컷)
+	ttUpper        // 상한 (알파를 못 넘김)
+)
+
+// 50수 규칙: 잡기·폰 이동 없이 이 반수가 지나면 무승부를 청구할 수 있습니다.
+const fiftyMoveLimit = 100
+
+// 치환표가 이 크기를 넘으면 비웁니다.
+const ttMaxSize = 1 << 20
+
+type ttEntry struct {
+	score float64
+	depth int
+	flag  int
+	best  *chess.Move // 이 포지션에서 가장 좋았던 수 (주 변화를 따라갈 때 씀)
+}
+
+// 탐색 한 번의 통계. nil이면 세지 않습니다.
+type searchStats struct {
+	Nodes int // 방문한 노드 수 (정지 탐색 포함)
+}
+
+func (s *searchStats) node() {
+	if s != nil {
+		s.Nodes++
+	}
+}
+
+// 이미 탐색한 포지션의 결과를 조브리스트 해시로 저장합니다.
+// 최상위 수를 고를 때마다 비웁니다. 고루틴마다 따로 써야 합니다.
+type transTable map[uint64]ttEntry
+
+// UCI 모드(bestMove)용 치환표. ai.mu 잠금 아래에서만 사용합니다.
+// /move는 탐색마다 치환표를 새로 만들어 잠금 없이 씁니다.
+var tt = make(transTable)
+
+// [탐색] 알파-베타 가지치기를 적용한 네가맥스 탐색입니다.
+// 반환값은 항상 pos에서 둘 차례인 쪽의 관점 점수입니다.
+func search(pos *chess.Position, depth int, alpha, beta float64) float64 {
+	return tt.search(pos, depth, alpha, beta)
+}
+
+// 정지 탐색의 최대 깊이. 잡기가 끝없이 이어지는 경우를 막습니다.
+const quiesceMaxDepth = 8
+
+// [탐색] 정지 탐색: 고정 깊이 끝에서 잡기·승격(첫 수는 체크도)만 이어서 두어
+// 조용한 포지션에서 평가합니다. 잡기 도중에 멈춰 잘못 평가하는 수평선 효과를 막습니다.
+// 둘 차례인 쪽은 잡지 않고 멈출 수도 있으므로 현재 평가값(stand pat)을 하한으로 씁니다.
+// ply는 루트에서 내려온 반수(메이트 거리), qdepth는 정지 탐색에 들어와 둔 반수입니다.
+func quiesce(pos *chess.Position, alpha, beta float64, ply, qdepth int, stats *searchStats) float64 {
+	stats.node()
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		if pos.Status() == chess.Checkmate {
+			return -(mateScore - float64(ply))
+		}
+		return drawScore(pos)
+	}
+
+	standPat := evaluate(pos)
+	if standPat >= beta || qdepth >= quiesceMaxDepth {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+	board := pos.Board()
+	for _, m := range noisyMoves(pos, moves, qdepth == 0) {
+		// 잡은 기물 값에 여유분을 더해도 알파에 못 미치는 잡기는 보지 않습니다. (델타 가지치기)
+		if m.HasTag(chess.Capture) && m.Promo() == chess.NoPieceType && !m.HasTag(chess.Check) &&
+			standPat+getPieceValue(board.Piece(m.S2()))+2*ai.PieceValues.Pawn < alpha {
+			continue
+		}
+		score := -quiesce(pos.Update(m), -beta, -alpha, ply+1, qdepth+1, stats)
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// 잡기·승격 수인지 봅니다.
+func isNoisy(m *chess.Move) bool {
+	return m.HasTag(chess.Capture) || m.HasTag(chess.EnPassant) || m.Promo() != chess.NoPieceType
+}
+
+// 같은 반수에서 베타 컷을 낸 조용한 수(킬러 무브)를 반수마다 두 개씩 기억합니다.
+// 형제 포지션에서도 같은 수가 컷을 낼 가능성이 높아 잡기 다음으로 먼저 봅니다.
+type killerTable [][2]*chess.Move
+
+// ply에서 컷을 낸 조용한 수를 맨 앞에 넣습니다. 이미 첫 번째면 그대로 둡니다.
+func (k killerTable) add(ply int, m *chess.Move) {
+	if ply >= len(k) || sameMove(k[ply][0], m) {
+		return
+	}
+	k[ply][1], k[ply][0] = k[ply][0], m
+}
+
+func (k killerTable) at(ply int) []*chess.Move {
+	if ply >= len(k) {
+		return nil
+	}
+	return k[ply][:]
+}
+
+// 포지션이 달라도 출발·도착 칸과 승격이 같으면 같은 수로 봅니다.
+func sameMove(a, b *chess.Move) bool {
+	return a != nil && b != nil && a.S1() == b.S1() && a.S2() == b.S2() && a.Promo() == b.Promo()
+}
+
+// 룩·비숍으로 승격하는 수인지 봅니다. 퀸이 룩·비숍의 길을 모두 가지므로 스테일메이트를 피할 때 말고는
+// 퀸 승격보다 나을 일이 없습니다. 나이트 승격은 퀸이 못 하는 포크·체크가 있어 따로 취급하지 않습니다.
+func isUnderpromotion(m *chess.Move) bool {
+	return m.Promo() == chess.Rook || m.Promo() == chess.Bishop
+}
+
+// 값싼 기물로 비싼 기물을 잡는 수부터(MVV-LVA), 그다음 킬러 무브 순서로 오도록 수를 정렬합니다.
+// 나머지 수는 원래 순서대로 뒤에 두고, 룩·비숍 승격은 맨 뒤에 둡니다. 좋은 수를 먼저 보면 가지치기가 많아집니다.
+func orderMoves(pos *chess.Position, moves []*chess.Move, killers ...*chess.Move) []*chess.Move {
+	board := pos.Board()
+	gain := make(map[*chess.Move]float64, len(moves))
+	for _, m := range moves {
+		if isUnderpromotion(m) {
+			gain[m] = -1
+			continue
+		}
+		if !isNoisy(m) {
+			for i, k := range killers {
+				if sameMove(m, k) {
+					gain[m] = float64(len(killers) - i)
+					break
+				}
+			}
+			continue
+		}
+		g := getPieceValue(board.Piece(m.S2()))*10 - getPieceValue(board.Piece(m.S1())) + 1000
+		if m.Promo() != chess.NoPieceType {
+			g += getPieceValue(chess.NewPiece(m.Promo(), pos.Turn())) * 10
+		}
+		gain[m] = g
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return gain[moves[i]] > gain[moves[j]]
+	})
+	return moves
+}
+
+// 정지 탐색에서 볼 수(잡기, 승격, withChecks면 체크)만 골라 정렬합니다.
+// 룩·비숍 승격은 같은 칸의 퀸 승격이 늘 있으므로 체크가 아니면 보지 않습니다.
+func noisyMoves(pos *chess.Position, moves []*chess.Move, withChecks bool) []*chess.Move {
+	var noisy []*chess.Move
+	for _, m := range moves {
+		if isUnderpromotion(m) && !(withChecks && m.HasTag(chess.Check)) {
+			continue
+		}
+		if isNoisy(m) || (withChecks && m.HasTag(chess.Check)) {
+			noisy = append(noisy, m)
+		}
+	}
+	if !ai.MoveOrdering {
+		return noisy
+	}
+	return orderMoves(pos, noisy)
+}
+
+// 무승부 포지션의 점수(둘 차례 관점). 평가상 앞선 쪽이면 -Contempt, 뒤진 쪽이면 +Contempt입니다.
+// Contempt가 양수면 이기고 있을 때 무승부로 가는 수를 피하고, 지고 있을 때는 찾아갑니다.
+// 수 선택에만 쓰이고 Q 학습 목표값에는 들어가지 않습니다. 실제로 비긴 판은 종료 보상
+// DrawReward(우세한 스테일메이트는 StaleReward)로 학습하므로, 두 값을 함께 낮추면 무승부를 더 피합니다.
+func drawScore(pos *chess.Position) float64 {
+	if ai.Contempt == 0 {
+		return 0
+	}
+	switch e := evaluate(pos); {
+	case e > 0:
+		return -ai.Contempt
+	case e < 0:
+		return ai.Contempt
+	}
+	return 0
+}
+
+func (tt transTable) search(pos *chess.Position, depth int, alpha, beta float64) float64 {
+	score, _ := tt.searchUntil(pos, depth, 0, alpha, beta, time.Time{}, nil)
+	return score
+}
+
+// search와 같지만 deadline(0이면 제한 없음)을 넘기면 바로 멈추고 ok=false를 돌려줍니다.
+// 멈춘 탐색의 점수는 믿을 수 없으므로 치환표에 넣지 않습니다. stats가 있으면 노드 수를 더합니다.
+// ply는 pos가 루트에서 몇 반수 아래인지입니다. 루트의 각 수를 둔 뒤 탐색하면 1이며, 메이트 거리를 잴 때 씁니다.
+func (tt transTable) searchUntil(pos *chess.Position, depth, ply int, alpha, beta float64, deadline time.Time, stats *searchStats) (float64, bool) {
+	return tt.negamax(pos, depth, ply, alpha, beta, deadline, make(killerTable, ply+max(depth, 0)), false, stats)
+}
+
+// pos에서 치환표에 남은 최선의 수를 따라가 최대 n수의 주 변화를 만듭니다.
+// 해시가 겹쳐 둘 수 없는 수가 나오거나 항목이 없으면 거기서 멈춥니다.
+func (tt transTable) principalVariation(pos *chess.Position, n int) []*chess.Move {
+	var pv []*chess.Move
+	for len(pv) < n {
+		e, ok := tt[Zobrist(pos)]
+		if !ok || e.best == nil {
+			break
+		}
+		var next *chess.Move
+		for _, m := range pos.ValidMoves() {
+			if sameMove(m, e.best) {
+				next = m
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		pv = append(pv, next)
+		pos = pos.Update(next)
+	}
+	return pv
+}
+
+// 널 무브 가지치기에서 줄이는 깊이. 남은 깊이가 이 이상이어야 시도하며, 줄인 깊이가 0 이하면 정지 탐색으로 봅니다.
+const nullMoveReduction = 2
+
+// 게임 단계(gamePhase)가 이 값보다 낮은 엔드게임에서는 널 무브를 쓰지 않습니다.
+// 기물이 적을수록 한 수 쉬는 것이 오히려 불리한 추크추방이 흔해 잘못 가지치기하기 쉽습니다.
+const nullMoveMinPhase = 0.25
+
+// 널 무브를 시도해도 되는 포지션인지 봅니다. 체크를 받고 있으면 쉴 수 없고,
+// 둘 차례인 쪽에 폰과 왕만 남았거나 엔드게임이면 추크추방 때문에 쓰지 않습니다.
+func nullMoveAllowed(pos *chess.Position) bool {
+	if gamePhase(pos) < nullMoveMinPhase {
+		return false
+	}
+	board := pos.Board()
+	var king chess.Square
+	pieces := false
+	for i := 0; i < 64; i++ {
+		p := board.Piece(chess.Square(i))
+		if p == chess.NoPiece || p.Color() != pos.Turn() {
+			continue
+		}
+		switch p.Type() {
+		case chess.King:
+			king = chess.Square(i)
+		case chess.Pawn:
+		default:
+			pieces = true
+		}
+	}
+	return pieces && attackMaps(board).count[pos.Turn().Other()][king] == 0
+}
+
+// ply는 루트에서 내려온 반수입니다. (메이트 거리, 킬러 무브 색인)
+// nullOK가 true면 널 무브 가지치기를 시도할 수 있습니다. 루트와 널 무브 바로 다음 노드는 false입니다.
+func (tt transTable) negamax(pos *chess.Position, depth, ply int, alpha, beta float64, deadline time.Time, killers killerTable, nullOK bool, stats *searchStats) (float64, bool) {
+	stats.node()
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		// 체크메이트는 루트에 가까울수록(더 빨리 당할수록) 나쁘게 평가합니다.
+		if pos.Status() == chess.Checkmate {
+			return -(mateScore - float64(ply)), true
+		}
+		return drawScore(pos), true // 스테일메이트
+	}
+	// 기물 부족이거나 50수 규칙으로 무승부를 청구할 수 있으면 무승부입니다.
+	if insufficientMaterial(pos) || pos.HalfMoveClock() >= fiftyMoveLimit {
+		return drawScore(pos), true
+	}
+	if depth <= 0 {
+		return quiesce(pos, alpha, beta, ply, 0, stats), true
+	}
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return 0, false
+	}
+
+	// 같은 깊이 이상으로 탐색해 둔 결과가 있으면 재사용합니다.
+	alphaOrig := alpha
+	key := Zobrist(pos)
+	if e, ok := tt[key]; ok && e.depth >= depth {
+		score := mateFromTT(e.score, ply)
+		switch e.flag {
+		case ttExact:
+			return score, true
+		case ttLower:
+			alpha = math.Max(alpha, score)
+		case ttUpper:
+			beta = math.Min(beta, score)
+		}
+		if alpha >= beta {
+			return score, true
+		}
+	}
+
+	// [탐색] 널 무브 가지치기: 한 수 쉬고(차례만 넘기고) 얕게 탐색해도 beta 이상이면
+	// 실제로 수를 두면 더 좋을 것이므로 이 노드를 잘라 냅니다. 메이트 점수 근처에서는 쓰지 않습니다.
+	if nullOK && ai.NullMove && depth >= nullMoveReduction && !math.IsInf(beta, 0) && !isMateScore(beta) && nullMoveAllowed(pos) {
+		if null := flipTurn(pos); null != nil {
+			score, ok := tt.negamax(null, depth-1-nullMoveReduction, ply+1, -beta, -beta+tieEpsilon, deadline, killers, false, stats)
+			if !ok {
+				return 0, false
+			}
+			if -score >= beta {
+				return beta, true
+			}
+		}
+	}
+
+	best := math.Inf(-1)
+	var bestMove *chess.Move
+	if ai.MoveOrdering {
+		moves = orderMoves(pos, moves, killers.at(ply)...)
+	}
+	for _, m := range moves {
+		score, ok := tt.negamax(pos.Update(m), depth-1, ply+1, -beta, -alpha, deadline, killers, true, stats)
+		if !ok {
+			return 0, false
+		}
+		score = -score
+		if score > best {
+			best, bestMove = score, m
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			if !isNoisy(m) {
+				killers.add(ply, m)
+			}
+			break
+		}
+	}
+
+	flag := ttExact
+	if best <= alphaOrig {
+		flag = ttUpper
+	} else if best >= beta {
+		flag = ttLower
+	}
+	if len(tt) >= ttMaxSize {
+		clear(tt)
+	}
+	tt[key] = ttEntry{score: mateToTT(best, ply), depth: depth, flag: flag, best: bestMove}
+	return best, true
+}
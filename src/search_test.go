@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestQuiesceResolvesPendingCapture(t *testing.T) {
+	newTestAI(t)
+	// 백 퀸이 e5의 폰을 잡았고 흑 차례. d6 폰이 퀸을 다시 잡을 수 있습니다.
+	pos := mustPos(t, "4k3/8/3p4/4Q3/8/8/8/4K3 b - - 0 1")
+	static := evaluate(pos)
+	var stats searchStats
+	q := quiesce(pos, math.Inf(-1), math.Inf(1), 0, 0, &stats)
+	if static > -ai.PieceValues.Queen/2 {
+		t.Fatalf("static eval %v does not see black a queen down", static)
+	}
+	if q < -ai.PieceValues.Pawn*2 {
+		t.Errorf("quiesce %v missed the recapture (static %v)", q, static)
+	}
+	if stats.Nodes < 2 {
+		t.Errorf("quiesce searched %d nodes", stats.Nodes)
+	}
+}
+
+func TestMateScoresCountPliesFromRoot(t *testing.T) {
+	newTestAI(t)
+	tests := []struct {
+		name  string
+		fen   string
+		depth int
+		move  string // 메이트하는 수가 하나뿐일 때만
+		plies int    // 루트에서 메이트까지의 반수
+	}{
+		{"mate in 1", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 2, "a1a8", 1},
+		{"mate in 2", "6k1/8/8/8/8/8/R7/1R4K1 w - - 0 1", 3, "", 3}, // Rb7(또는 Ra7) 뒤 다른 룩으로 메이트
+	}
+	for _, tt := range tests {
+		pos := mustPos(t, tt.fen)
+		ranked, _ := rankAtDepth(pos, pos.ValidMoves(), nil, make(transTable), tt.depth, time.Time{}, nil)
+		if got := ranked[0].move.String(); tt.move != "" && got != tt.move {
+			t.Errorf("%s: best move %s, want %s", tt.name, got, tt.move)
+		}
+		if want := mateScore - float64(tt.plies); ranked[0].eval != want {
+			t.Errorf("%s: eval %v, want %v", tt.name, ranked[0].eval, want)
+		}
+		if !isMateScore(ranked[0].eval) {
+			t.Errorf("%s: %v not recognized as a mate score", tt.name, ranked[0].eval)
+		}
+	}
+}
+
+func TestPrefersFasterMate(t *testing.T) {
+	newTestAI(t)
+	// Ra8#로 바로 메이트할 수 있고, 다른 룩을 먼저 움직여도 곧 메이트합니다.
+	pos := mustPos(t, "6k1/5ppp/8/8/8/8/5PPP/R4RK1 w - - 0 1")
+	ranked, depth := rankMovesTimed(pos, pos.ValidMoves(), nil, make(transTable), 200*time.Millisecond, nil)
+	if ranked[0].eval != mateScore-1 {
+		t.Errorf("depth %d: best %s eval %v, want mate in 1", depth, ranked[0].move, ranked[0].eval)
+	}
+	for _, s := range ranked[1:] {
+		if s.eval >= ranked[0].eval {
+			t.Errorf("%s (eval %v) ties the mate in 1", s.move, s.eval)
+		}
+	}
+}
+
+// 잡기가 많이 걸린 전술 포지션에서 수 정렬(MVV-LVA, 킬러 무브)을 켠 탐색과 끈 탐색의
+// op당 노드 수(nodes/op)를 비교합니다. (깊이 3에서는 정렬하지 않은 탐색이 너무 오래 걸려 깊이 2로 잽니다)
+func BenchmarkMoveOrdering(b *testing.B) {
+	newTestAI(b)
+	ai.NullMove = false
+	pos := mustPos(b, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	inf := math.Inf(1)
+	for _, ordered := range []bool{false, true} {
+		name := "unordered"
+		if ordered {
+			name = "ordered"
+		}
+		b.Run(name, func(b *testing.B) {
+			ai.MoveOrdering = ordered
+			var stats searchStats
+			for i := 0; i < b.N; i++ {
+				tt := make(transTable)
+				tt.searchUntil(pos, 2, 0, -inf, inf, time.Time{}, &stats)
+			}
+			b.ReportMetric(float64(stats.Nodes)/float64(b.N), "nodes/op")
+		})
+	}
+}
+
+// 널 무브 가지치기를 켠 탐색과 끈 탐색의 op당 노드 수(nodes/op)를 비교합니다.
+// 널 무브는 루트에서 두 수 아래부터 쓰므로 깊이 4로 잽니다. 같은 포지션에서 고른 수가 달라지면 실패합니다.
+func BenchmarkNullMove(b *testing.B) {
+	newTestAI(b)
+	positions := []*chess.Position{
+		mustPos(b, middlegameFEN),
+		mustPos(b, "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"),
+	}
+	best := make(map[bool][]string)
+	for _, nullMove := range []bool{false, true} {
+		name := "off"
+		if nullMove {
+			name = "on"
+		}
+		b.Run(name, func(b *testing.B) {
+			ai.NullMove = nullMove
+			var stats searchStats
+			for i := 0; i < b.N; i++ {
+				best[nullMove] = best[nullMove][:0]
+				for _, pos := range positions {
+					ranked, _ := rankAtDepth(pos, pos.ValidMoves(), nil, make(transTable), 4, time.Time{}, &stats)
+					best[nullMove] = append(best[nullMove], ranked[0].move.String())
+				}
+			}
+			b.ReportMetric(float64(stats.Nodes)/float64(b.N), "nodes/op")
+		})
+	}
+	// -bench로 한쪽만 돌렸으면 비교하지 않습니다.
+	if len(best[false]) != len(positions) || len(best[true]) != len(positions) {
+		return
+	}
+	for i := range positions {
+		if best[false][i] != best[true][i] {
+			b.Errorf("position %d: best move %s with null move, %s without", i, best[true][i], best[false][i])
+		}
+	}
+}
+
+func TestSearchStatsGrowWithDepth(t *testing.T) {
+	newTestAI(t)
+	const fen = "r3k3/ppp2n2/8/8/8/8/PPP2N2/R3K3 w - - 0 1"
+	// /move는 search_depth까지 탐색하고, 깊을수록 더 많은 노드를 봅니다.
+	prev := 0.0
+	for depth := 1; depth <= 3; depth++ {
+		ai.SearchDepth = depth
+		rec := postJSON(t, moveHandler, "/move", map[string]string{"fen": fen, "session_id": fmt.Sprint("d", depth)})
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		nodes, _ := resp["nodes_searched"].(float64)
+		if resp["depth_reached"] != float64(depth) || nodes <= prev {
+			t.Errorf("/move depth %d: depth_reached %v nodes_searched %v (previous depth %v)", depth, resp["depth_reached"], resp["nodes_searched"], prev)
+		}
+		if ms, ok := resp["time_ms"].(float64); !ok || ms < 0 {
+			t.Errorf("/move depth %d: time_ms = %v", depth, resp["time_ms"])
+		}
+		prev = nodes
+	}
+
+	// /solve도 같은 탐색 정보를 돌려줍니다.
+	prev = 0
+	for depth := 1; depth <= 3; depth++ {
+		resp := solve(t, map[string]interface{}{"fen": fen, "depth": depth})
+		nodes, _ := resp["nodes_searched"].(float64)
+		if resp["depth_reached"] != float64(depth) || nodes <= prev {
+			t.Errorf("/solve depth %d: depth_reached %v nodes_searched %v (previous depth %v)", depth, resp["depth_reached"], resp["nodes_searched"], prev)
+		}
+		if ms, ok := resp["time_ms"].(float64); !ok || ms < 0 {
+			t.Errorf("/solve depth %d: time_ms = %v", depth, resp["time_ms"])
+		}
+		prev = nodes
+	}
+}
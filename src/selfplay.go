@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// 셀프 플레이 한 판의 최대 반수. 넘으면 무승부로 끝냅니다.
+const selfPlayMaxPlies = 300
+
+// POST /selfplay 한 번에 둘 수 있는 최대 판 수. 요청은 모든 판이 끝날 때까지 기다리므로,
+// 더 길게 학습하려면 -selfplay 플래그를 쓰거나 여러 번 나눠 요청합니다.
+const maxSelfPlayGames = 1000
+
+type sideStats struct {
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+}
+
+type selfPlayStats struct {
+	Games int       `json:"games"`
+	Plies int       `json:"plies"`
+	White sideStats `json:"white"`
+	Black sideStats `json:"black"`
+}
+
+// 결과 한 판을 색별 승/패/무로 집계합니다.
+func (s *selfPlayStats) record(outcome chess.Outcome) {
+	s.Games++
+	switch outcome {
+	case chess.WhiteWon:
+		s.White.Wins++
+		s.Black.Losses++
+	case chess.BlackWon:
+		s.Black.Wins++
+		s.White.Losses++
+	default:
+		s.White.Draws++
+		s.Black.Draws++
+	}
+}
+
+// 셀프 플레이 작업자. 난수 생성기와 치환표는 고루틴마다 따로 가집니다.
+type selfPlayWorker struct {
+	rng *rand.Rand
+	tt  transTable
+}
+
+func newSelfPlayWorker(seed int64) *selfPlayWorker {
+	return &selfPlayWorker{
+		rng: rand.New(rand.NewSource(seed)),
+		tt:  make(transTable),
+	}
+}
+
+// /move의 chooseMove처럼 Q값과 설정은 ai.mu 아래에서 복사만 하고, 탐색은 configMu 읽기 잠금만 잡은 채로 합니다.
+// 탐색 중에는 POST /config만 기다리고, 학습·저장·다른 요청은 ai.mu를 바로 잡을 수 있습니다.
+// 선택 방식은 /move와 같은 rankMoves, pickMove입니다.
+func (w *selfPlayWorker) chooseMove(pos *chess.Position) *chess.Move {
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+	configMu.RLock()
+	defer configMu.RUnlock()
+	state := stateKey(pos)
+	ai.mu.RLock()
+	qrow := qValues(state)
+	cfg := ai.Config
+	cfg.Epsilon = cfg.effectiveEpsilon(ai.GameCount)
+	ai.mu.RUnlock()
+	ranked := rankMoves(pos, moves, qrow, w.tt, nil)
+
+	move, _ := pickMove(ranked, moves, w.rng, cfg)
+	return move
+}
+
+// [학습] AI끼리 한 판을 두고 양쪽 기록으로 학습합니다.
+func (w *selfPlayWorker) playGame() (chess.Outcome, int) {
+	histories := make(map[chess.Color][]string)
+
+	game := chess.NewGame()
+	for game.Outcome() == chess.NoOutcome && len(game.Moves()) < selfPlayMaxPlies {
+		pos := game.Position()
+		move := w.chooseMove(pos)
+		if move == nil {
+			break
+		}
+		// 수 기록의 보상(shapedReward)도 평가 설정을 읽으므로 configMu 읽기 잠금 아래에서 만듭니다.
+		configMu.RLock()
+		histories[pos.Turn()] = recordMove(histories[pos.Turn()], pos, move)
+		configMu.RUnlock()
+		if !playMove(game, move) {
+			break
+		}
+		// 같은 국면이 세 번 나오면 무승부를 선언해 무한 반복을 막습니다.
+		for _, m := range game.EligibleDraws() {
+			if m == chess.ThreefoldRepetition {
+				game.Draw(m)
+			}
+		}
+	}
+
+	result := "Draw"
+	switch game.Outcome() {
+	case chess.WhiteWon:
+		result = chess.White.Name()
+	case chess.BlackWon:
+		result = chess.Black.Name()
+	}
+	mergeLearning(histories, result, game.Position().String(), w.rng)
+
+	outcome := game.Outcome()
+	if outcome == chess.NoOutcome {
+		outcome = chess.Draw // 최대 반수 초과
+	}
+	return outcome, len(game.Moves())
+}
+
+// 기록에 나온 상태들의 Q값을 복사해 잠금 없이 학습한 뒤, 변화량만 쓰기 잠금 아래에서
+// 공유 Q테이블과 방문 횟수 표에 더합니다. 여러 작업자가 같은 상태를 갱신해도 변화량이 합쳐집니다.
+func mergeLearning(histories map[chess.Color][]string, result, fen string, r *rand.Rand) {
+	local := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	visits := make(map[string]map[string]int)
+	rewards := make(map[chess.Color]float64)
+	ai.mu.RLock()
+	for color, history := range histories {
+		for _, record := range history {
+			from, _, _, next, ok := parseRecord(record)
+			if !ok {
+				continue
+			}
+			states := []string{from, next}
+			if ai.Augment {
+				// 대칭 학습으로 함께 갱신할 좌우 반전 상태도 복사해 둡니다.
+				states = append(states, mirrorFEN(from), mirrorFEN(next))
+			}
+			for _, state := range states {
+				if _, ok := local.a[state]; ok || state == "" {
+					continue
+				}
+				local.a[state] = copyRow(ai.QTable[state])
+				local.b[state] = copyRow(ai.QB[state])
+				counts := make(map[string]int, len(ai.Visits[state]))
+				for move, n := range ai.Visits[state] {
+					counts[move] = n
+				}
+				visits[state] = counts
+			}
+		}
+		rewards[color], _ = terminalReward(result, fen, color)
+	}
+	alpha := ai.effectiveAlpha(ai.GameCount)
+	ai.mu.RUnlock()
+
+	before := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	for state := range local.a {
+		before.a[state] = copyRow(local.a[state])
+		before.b[state] = copyRow(local.b[state])
+	}
+	visitsBefore := make(map[string]map[string]int, len(visits))
+	for state, counts := range visits {
+		visitsBefore[state] = make(map[string]int, len(counts))
+		for move, n := range counts {
+			visitsBefore[state][move] = n
+		}
+	}
+	var transitions []transition
+	for color, history := range histories {
+		transitions = append(transitions, learnInto(local, visits, history, rewards[color], alpha, r)...)
+	}
+
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	ai.GameCount++
+	for state := range local.a {
+		if ai.QTable[state] == nil {
+			ai.QTable[state] = make(map[string]float64)
+		}
+		markDirty(state)
+		addDeltas(ai.QTable, state, local.a[state], before.a[state])
+		addDeltas(ai.QB, state, local.b[state], before.b[state])
+	}
+	for state, counts := range visits {
+		for move, n := range counts {
+			if d := n - visitsBefore[state][move]; d != 0 {
+				if ai.Visits[state] == nil {
+					ai.Visits[state] = make(map[string]int)
+				}
+				ai.Visits[state][move] += d
+			}
+		}
+	}
+	replayExperience(transitions)
+	evictStates()
+	recordProgress()
+}
+
+func copyRow(row map[string]float64) map[string]float64 {
+	c := make(map[string]float64, len(row))
+	for move, q := range row {
+		c[move] = q
+	}
+	return c
+}
+
+// 학습 전(before)과 후(after) 행의 차이만 table의 state 행에 더합니다.
+func addDeltas(table map[string]map[string]float64, state string, after, before map[string]float64) {
+	for move, q := range after {
+		if d := q - before[move]; d != 0 {
+			if table[state] == nil {
+				table[state] = make(map[string]float64)
+			}
+			table[state][move] += d
+		}
+	}
+}
+
+// 셀프 플레이 작업자 수. 0 이하면 CPU 개수입니다.
+// 시드를 고정했으면(fixedSeed) 작업자끼리 학습을 합치는 순서가 실행마다 달라지지 않도록 하나만 씁니다.
+func selfPlayWorkers(workers int) int {
+	if fixedSeed {
+		if workers != 1 {
+			slog.Info("시드를 고정해 셀프 플레이 작업자를 하나만 씁니다", "requested", workers)
+		}
+		return 1
+	}
+	if workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return workers
+}
+
+// n판의 셀프 플레이를 workers개의 고루틴에 나눠 두고 결과를 집계합니다.
+func runSelfPlay(n, workers int) selfPlayStats {
+	workers = selfPlayWorkers(workers)
+
+	// 작업자 시드는 공유 난수 생성기에서 뽑아 -seed로 재현할 수 있게 합니다.
+	seeds := make([]int64, workers)
+	ai.mu.Lock()
+	for i := range seeds {
+		seeds[i] = ai.rng.Int63()
+	}
+	ai.mu.Unlock()
+
+	var (
+		stats   selfPlayStats
+		statsMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	jobs := make(chan struct{})
+	for _, seed := range seeds {
+		wg.Add(1)
+		go func(w *selfPlayWorker) {
+			defer wg.Done()
+			for range jobs {
+				outcome, plies := w.playGame()
+				statsMu.Lock()
+				stats.record(outcome)
+				stats.Plies += plies
+				statsMu.Unlock()
+			}
+		}(newSelfPlayWorker(seed))
+	}
+	for i := 0; i < n; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	saveToFile()
+	return stats
+}
+
+// POST /selfplay {"games": N} (N은 최대 maxSelfPlayGames)
+func selfPlayHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		Games   int `json:"games"`
+		Workers int `json:"workers"` // 0이면 CPU 개수, 시드를 고정했으면 1
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Games <= 0 || req.Games > maxSelfPlayGames {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("games must be between 1 and %d", maxSelfPlayGames))
+		return
+	}
+
+	stats := runSelfPlay(req.Games, req.Workers)
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestSelfPlayHandlerCapsGames(t *testing.T) {
+	newTestAI(t)
+	for _, body := range []string{`{"games": 0}`, fmt.Sprintf(`{"games": %d}`, maxSelfPlayGames+1), `{`} {
+		rec := httptest.NewRecorder()
+		selfPlayHandler(rec, httptest.NewRequest(http.MethodPost, "/selfplay", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, rec.Code)
+		}
+	}
+	if ai.GameCount != 0 {
+		t.Errorf("rejected request played %d games", ai.GameCount)
+	}
+}
+
+// 같은 시드의 작업자는 같은 Q테이블에서 같은 수순을 둡니다. (epsilon 탐색 포함)
+func TestSelfPlaySameSeedSameMoves(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth, ai.Epsilon = 1, 0.3
+	play := func(seed int64) []string {
+		w := newSelfPlayWorker(seed)
+		game := chess.NewGame()
+		var moves []string
+		for len(moves) < 12 && game.Outcome() == chess.NoOutcome {
+			m := w.chooseMove(game.Position())
+			moves = append(moves, m.String())
+			game.Move(m)
+		}
+		return moves
+	}
+	a, b := play(42), play(42)
+	if strings.Join(a, " ") != strings.Join(b, " ") {
+		t.Errorf("same seed diverged:\n%v\n%v", a, b)
+	}
+	if c := play(43); strings.Join(a, " ") == strings.Join(c, " ") {
+		t.Errorf("different seeds played the same %d moves", len(a))
+	}
+}
+
+func TestFixedSeedUsesOneWorker(t *testing.T) {
+	old := fixedSeed
+	defer func() { fixedSeed = old }()
+	fixedSeed = true
+	if n := selfPlayWorkers(4); n != 1 {
+		t.Errorf("fixed seed: %d workers, want 1", n)
+	}
+	fixedSeed = false
+	if n := selfPlayWorkers(4); n != 4 {
+		t.Errorf("%d workers, want 4", n)
+	}
+	if n := selfPlayWorkers(0); n != runtime.NumCPU() {
+		t.Errorf("default %d workers, want NumCPU %d", n, runtime.NumCPU())
+	}
+}
+
+// 작업자 수에 따른 셀프 플레이 처리량(games/s). 빠르게 돌도록 탐색 깊이는 1로 둡니다.
+// 작업자는 CPU 개수까지만 늘려 봅니다. (CPU가 하나면 1만)
+func BenchmarkSelfPlay(b *testing.B) {
+	for workers := 1; workers <= runtime.NumCPU(); workers *= 2 {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			newTestAI(b)
+			ai.SearchDepth = 1
+			const games = 4
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				runSelfPlay(games, workers)
+			}
+			b.ReportMetric(float64(games*b.N)/time.Since(start).Seconds(), "games/s")
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// 세션 하나에 남기는 최대 반수. 넘으면 오래된 기록부터 버립니다.
+const maxSessionPlies = 1000
+
+// 세션 하나의 기록. ai.mu 잠금 아래에서만 읽고 씁니다.
+// history는 학습용이라 AI가 둔 수만 "state|move|reward|nextState"로 담고,
+// game은 양쪽이 실제로 둔 수와 각 수를 두기 전 포지션을 순서대로 담아 PGN, 반복 회피, 재구성에 씁니다.
+type session struct {
+	history  []string
+	game     *chess.Game
+	lastSeen time.Time
+}
+
+// 세션을 찾고, 없으면 만듭니다. 세션은 첫 요청 때 만들어집니다.
+func getSession(id string) *session {
+	s := ai.sessions[id]
+	if s == nil {
+		s = &session{lastSeen: time.Now()}
+		ai.sessions[id] = s
+	}
+	return s
+}
+
+// AI가 pos에서 둔 수를 학습 기록에 남깁니다. maxSessionPlies를 넘으면 앞에서부터 버립니다.
+func (s *session) record(pos *chess.Position, move *chess.Move) {
+	s.history = recordMove(s.history, pos, move)
+	if n := len(s.history); n > maxSessionPlies {
+		s.history = append([]string(nil), s.history[n-maxSessionPlies:]...)
+	}
+}
+
+// 실제로 둔 수 하나
+type playedMove struct {
+	Ply int    `json:"ply"`
+	FEN string `json:"fen"` // 수를 두기 전 포지션
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+}
+
+// 기록된 대국의 수를 둔 순서대로 돌려줍니다.
+// 첫 수의 fen에서 UCI 수를 차례로 두면 대국을 그대로 다시 만들 수 있습니다.
+func (s *session) played() []playedMove {
+	moves := []playedMove{}
+	if s.game == nil {
+		return moves
+	}
+	positions := s.game.Positions()
+	for i, m := range s.game.Moves() {
+		moves = append(moves, playedMove{
+			Ply: i + 1,
+			FEN: positions[i].String(),
+			UCI: m.String(),
+			SAN: chess.AlgebraicNotation{}.Encode(positions[i], m),
+		})
+	}
+	return moves
+}
+
+// GET /session?session_id=ID
+// 진행 중인 세션에서 양쪽이 실제로 둔 수(수마다 두기 전 FEN)와 현재 포지션을 돌려줍니다.
+// 없는 세션(끝났거나 정리된 세션 포함)이면 404입니다.
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	id := r.URL.Query().Get("session_id")
+	ai.mu.RLock()
+	defer ai.mu.RUnlock()
+	s := ai.sessions[id]
+	if s == nil || s.game == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id": id,
+		"fen":        s.game.Position().String(),
+		"moves":      s.played(),
+		"learned":    len(s.history),
+	})
+}
+
+// POST /newgame으로 만든 세션 ID에 붙이는 번호
+var newGameSeq atomic.Uint64
+
+// POST /newgame {"fen": "..."}
+// 주어진 시작 포지션(비우면 표준 시작)에서 새 세션을 만들고
+// session_id를 돌려줍니다. 이후 /move에 이 session_id를 보내면 이 포지션에서 이어 두며,
+// 그때 fen을 비우면 세션의 현재 포지션을 씁니다.
+// 읽을 수 없는 FEN이거나 이미 끝난 포지션이면 400입니다.
+func newGameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		FEN string `json:"fen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	fen := req.FEN
+	if fen == "" {
+		fen = chess.StartingPosition().String()
+	}
+	game, err := parseFEN(fen)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+	claimFiftyMove(game)
+	if game.Outcome() != chess.NoOutcome {
+		writeError(w, http.StatusBadRequest, "game is already over: "+game.Method().String())
+		return
+	}
+
+	id := fmt.Sprintf("game-%d", newGameSeq.Add(1))
+	s := getSession(id)
+	s.game = newRecordedGame(game.Position())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id": id,
+		"fen":        game.Position().String(),
+		"turn":       game.Position().Turn().Name(),
+	})
+}
+
+// /move 요청에서 비운 fen을 세션의 현재 포지션으로 채웁니다.
+func fillFromSession(req *moveRequest) {
+	ai.mu.RLock()
+	defer ai.mu.RUnlock()
+	s := ai.sessions[req.SessionID]
+	if s == nil {
+		return
+	}
+	if req.FEN == "" && s.game != nil {
+		req.FEN = s.game.Position().String()
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// /move 응답을 읽습니다.
+func moveResponse(t *testing.T, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	rec := postJSON(t, moveHandler, "/move", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSessionReconstructsGame(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	game := chess.NewGame()
+	for _, uci := range []string{"e2e4", "g1f3", "f1c4"} {
+		// 사람(백)이 두고, AI(흑)가 답합니다. 백의 수가 합법이 아니면 다른 합법 수를 둡니다.
+		m, err := chess.UCINotation{}.Decode(game.Position(), uci)
+		if err != nil || game.Move(m) != nil {
+			if err := game.Move(game.ValidMoves()[0]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp := moveResponse(t, map[string]interface{}{"fen": game.Position().String(), "color": "black", "session_id": "replay"})
+		if err := game.Move(mustMove(t, game.Position(), resp["move"].(string))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	sessionHandler(rec, httptest.NewRequest(http.MethodGet, "/session?session_id=replay", nil))
+	var got struct {
+		FEN   string       `json:"fen"`
+		Moves []playedMove `json:"moves"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Moves) != 6 {
+		t.Fatalf("stored %d moves, want 6: %+v", len(got.Moves), got.Moves)
+	}
+	// 첫 수의 FEN에서 저장된 UCI 수를 차례로 두면 같은 대국이 됩니다.
+	opt, err := chess.FEN(got.Moves[0].FEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilt := chess.NewGame(opt)
+	for i, pm := range got.Moves {
+		if pm.FEN != rebuilt.Position().String() {
+			t.Errorf("ply %d: stored FEN %q, rebuilt %q", pm.Ply, pm.FEN, rebuilt.Position())
+		}
+		if want := game.Moves()[i].String(); pm.UCI != want {
+			t.Errorf("ply %d: stored %s, played %s", pm.Ply, pm.UCI, want)
+		}
+		if err := rebuilt.Move(mustMove(t, rebuilt.Position(), pm.UCI)); err != nil {
+			t.Fatalf("ply %d: %v", pm.Ply, err)
+		}
+	}
+	if rebuilt.Position().String() != game.Position().String() || got.FEN != game.Position().String() {
+		t.Errorf("rebuilt %q, session %q, want %q", rebuilt.Position(), got.FEN, game.Position())
+	}
+
+	// 대국이 끝나면 세션 기록을 지웁니다.
+	moveResponse(t, map[string]interface{}{"fen": game.Position().String(), "color": "black", "session_id": "replay", "result": "Draw"})
+	if _, ok := ai.sessions["replay"]; ok {
+		t.Error("session kept after the game ended")
+	}
+}
+
+func TestNewGameFromEndgameFEN(t *testing.T) {
+	newTestAI(t)
+	ai.SearchDepth = 1
+	fen := "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"
+	rec := postJSON(t, newGameHandler, "/newgame", map[string]interface{}{"fen": fen})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := created["session_id"].(string)
+	if id == "" || created["turn"] != "White" {
+		t.Fatalf("newgame = %v", created)
+	}
+
+	// fen을 비우고 session_id만 보내면 세션의 시작 포지션에서 둡니다.
+	resp := moveResponse(t, map[string]interface{}{"session_id": id, "color": "white"})
+	start := mustPos(t, fen)
+	legal := false
+	for _, m := range start.ValidMoves() {
+		legal = legal || m.String() == resp["move"]
+	}
+	if !legal {
+		t.Errorf("move %v is not legal in the endgame start", resp["move"])
+	}
+	if played := ai.sessions[id].played(); len(played) != 1 || played[0].FEN != start.String() {
+		t.Errorf("session moves = %+v, want one move from %s", played, fen)
+	}
+
+	for _, bad := range []string{"not a fen", "8/8/8/8/8/8/8/8 w - - 0 1", "4k3/4Q3/4K3/8/8/8/8/8 b - - 0 1"} {
+		if rec := postJSON(t, newGameHandler, "/newgame", map[string]interface{}{"fen": bad}); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", bad, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// /solve에서 받는 최대 탐색 깊이 (반수)
+const maxSolveDepth = 6
+
+// [탐색] depth 반수까지 매 수마다 최선의 수를 골라 주 변화(PV)를 만듭니다.
+// 각 수는 남은 깊이로 전체 창 탐색(정지 탐색 포함)한 점수로 고르며, score는 첫 수의 점수(둘 차례 관점)입니다.
+// 판이 끝나면 그 자리에서 멈춥니다. deadline(0이면 제한 없음)을 넘기면 ok가 false입니다.
+func solveLine(pos *chess.Position, depth int, tt transTable, deadline time.Time, stats *searchStats) (pv []*chess.Move, score float64, ok bool) {
+	for d := depth; d > 0; d-- {
+		moves := pos.ValidMoves()
+		if len(moves) == 0 {
+			break
+		}
+		var best *chess.Move
+		bestScore := math.Inf(-1)
+		for _, m := range orderMoves(pos, moves) {
+			s, ok := tt.searchUntil(pos.Update(m), d-1, len(pv)+1, math.Inf(-1), math.Inf(1), deadline, stats)
+			if !ok {
+				return nil, 0, false
+			}
+			if -s > bestScore {
+				best, bestScore = m, -s
+			}
+		}
+		if len(pv) == 0 {
+			score = bestScore
+		}
+		pv = append(pv, best)
+		pos = pos.Update(best)
+	}
+	return pv, score, true
+}
+
+// POST /solve {"fen": ..., "depth": N, "time_ms": T}
+// 퍼즐용: Q값·epsilon·오프닝 북 없이 탐색만으로 최선의 수와 주 변화를 돌려줍니다.
+// 학습하거나 세션 기록을 남기지 않습니다. depth를 생략하면(0) search_depth를 씁니다.
+// time_ms를 주면 깊이 1부터 늘려 가며 시간 안에 끝난 가장 깊은 결과를 돌려주고, 이때 depth를 주면 그 깊이까지만 늘립니다.
+// Q테이블은 읽지 않으므로 ai.mu 없이 configMu 읽기 잠금만 잡고 탐색합니다.
+func solveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	var req struct {
+		FEN    string `json:"fen"`
+		Depth  int    `json:"depth"`
+		TimeMs int    `json:"time_ms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Depth < 0 || req.Depth > maxSolveDepth {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("depth must be between 1 and %d (0 or omitted uses search_depth)", maxSolveDepth))
+		return
+	}
+	game, err := parseFEN(req.FEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid FEN: "+err.Error())
+		return
+	}
+	pos := game.Position()
+	if len(pos.ValidMoves()) == 0 {
+		writeError(w, http.StatusBadRequest, "no legal moves: "+pos.Status().String())
+		return
+	}
+
+	configMu.RLock()
+	depth := req.Depth
+	if depth == 0 {
+		depth = ai.SearchDepth
+	}
+	tt := make(transTable)
+	var stats searchStats
+	start := time.Now()
+	pv, score, _ := solveLine(pos, 1, tt, time.Time{}, &stats)
+	if req.TimeMs > 0 {
+		limit := maxIterDepth
+		if req.Depth > 0 {
+			limit = req.Depth
+		}
+		depth = 1
+		deadline := time.Now().Add(time.Duration(req.TimeMs) * time.Millisecond)
+		for d := 2; d <= limit && time.Now().Before(deadline); d++ {
+			line, s, ok := solveLine(pos, d, tt, deadline, &stats)
+			if !ok {
+				break
+			}
+			pv, score, depth = line, s, d
+			if isMateScore(score) {
+				break
+			}
+		}
+	} else if depth > 1 {
+		pv, score, _ = solveLine(pos, depth, tt, time.Time{}, &stats)
+	}
+	configMu.RUnlock()
+	elapsed := time.Since(start)
+
+	uci := make([]string, len(pv))
+	san := make([]string, len(pv))
+	end := pos
+	for i, m := range pv {
+		uci[i] = m.String()
+		san[i] = chess.AlgebraicNotation{}.Encode(end, m)
+		end = end.Update(m)
+	}
+	resp := map[string]interface{}{
+		"move":   uci[0],
+		"san":    san[0],
+		"pv":     uci,
+		"pv_san": san,
+		"score":  score,
+		"depth":  depth,
+		// 탐색 정보. 시간 제한이 있으면 끝내지 못한 깊이의 노드도 nodes_searched에 들어갑니다.
+		"nodes_searched": stats.Nodes,
+		"depth_reached":  depth,
+		"time_ms":        elapsed.Milliseconds(),
+	}
+	// 주 변화가 둘 차례인 쪽의 체크메이트로 끝나면 몇 수 메이트인지 알려 줍니다.
+	if end.Status() == chess.Checkmate && len(pv)%2 == 1 {
+		resp["mate_in"] = (len(pv) + 1) / 2
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func solve(t *testing.T, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	rec := postJSON(t, solveHandler, "/solve", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSolveMateInTwo(t *testing.T) {
+	newTestAI(t)
+	// 두 룩의 사다리 메이트 (예: Ra7 뒤 Rb8#)
+	resp := solve(t, map[string]interface{}{"fen": "6k1/8/8/8/8/8/R7/1R4K1 w - - 0 1", "depth": 3})
+	if resp["mate_in"] != 2.0 {
+		t.Fatalf("mate_in = %v, want 2 (pv %v)", resp["mate_in"], resp["pv_san"])
+	}
+	if !isMateScore(resp["score"].(float64)) || resp["score"].(float64) < 0 {
+		t.Errorf("score = %v, want a winning mate score", resp["score"])
+	}
+	if pv := resp["pv"].([]interface{}); len(pv) != 3 {
+		t.Errorf("pv = %v, want three plies", pv)
+	}
+}
+
+func TestSolveDepthAndTimeLimit(t *testing.T) {
+	newTestAI(t)
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	if rec := postJSON(t, solveHandler, "/solve", map[string]interface{}{"fen": fen, "depth": maxSolveDepth + 1}); rec.Code != http.StatusBadRequest {
+		t.Errorf("depth %d: status %d, want 400", maxSolveDepth+1, rec.Code)
+	}
+	// time_ms와 depth를 함께 주면 depth까지만 깊어집니다.
+	resp := solve(t, map[string]interface{}{"fen": fen, "depth": 2, "time_ms": 60000})
+	if resp["depth_reached"] != 2.0 {
+		t.Errorf("depth_reached = %v, want the depth cap 2", resp["depth_reached"])
+	}
+}
+
+func TestSolveDoesNotHoldQTableLock(t *testing.T) {
+	newTestAI(t)
+	ai.mu.Lock()
+	done := make(chan map[string]interface{})
+	go func() {
+		done <- solve(t, map[string]interface{}{"fen": "6k1/8/8/8/8/8/R7/1R4K1 w - - 0 1", "depth": 1})
+	}()
+	select {
+	case resp := <-done:
+		ai.mu.Unlock()
+		if resp["move"] == nil {
+			t.Errorf("resp = %v", resp)
+		}
+	case <-time.After(10 * time.Second):
+		ai.mu.Unlock()
+		<-done
+		t.Fatal("/solve waited for ai.mu")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"chess-ai/stockfish"
+
+	"github.com/notnil/chess"
+)
+
+// 스파링 결과 (AI 관점)
+type sparringStats struct {
+	Games  int     `json:"games"`
+	Plies  int     `json:"plies"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Rating float64 `json:"rating"` // 마지막 판을 반영한 Elo 레이팅
+}
+
+// [학습] 외부 UCI 엔진(Stockfish)을 상대로 n판을 둡니다. 색은 판마다 번갈아 맡습니다.
+// AI의 수는 /move와 같은 chooseMove로 고르고, 판이 끝나면 finishGame으로 학습·레이팅 갱신·PGN 저장을 합니다.
+// 엔진이 다시 띄워도 응답하지 않으면 그때까지의 결과와 함께 오류를 돌려줍니다.
+func runSparring(eng *stockfish.Engine, n int, rating float64) (sparringStats, error) {
+	var stats sparringStats
+	defer saveToFile()
+	for i := 0; i < n; i++ {
+		aiColor := chess.White
+		if i%2 == 1 {
+			aiColor = chess.Black
+		}
+		outcome, plies, err := playSparringGame(eng, aiColor, fmt.Sprintf("sparring-%d", i), rating)
+		if err != nil {
+			return stats, err
+		}
+		stats.Games++
+		stats.Plies += plies
+		switch {
+		case outcome == chess.Draw:
+			stats.Draws++
+		case (outcome == chess.WhiteWon) == (aiColor == chess.White):
+			stats.Wins++
+		default:
+			stats.Losses++
+		}
+	}
+	ai.mu.RLock()
+	stats.Rating = ai.Rating
+	ai.mu.RUnlock()
+	return stats, nil
+}
+
+// 엔진과 한 판을 둡니다. 세 번 반복되거나 selfPlayMaxPlies를 넘으면 무승부입니다.
+func playSparringGame(eng *stockfish.Engine, aiColor chess.Color, sessionID string, rating float64) (chess.Outcome, int, error) {
+	game := chess.NewGame()
+	var played []string
+	for game.Outcome() == chess.NoOutcome && len(game.Moves()) < selfPlayMaxPlies {
+		pos := game.Position()
+		var move *chess.Move
+		if pos.Turn() == aiColor {
+			move = chooseMove(sessionID, pos, 0, learningEnabled(nil)).move
+		} else {
+			uci, err := eng.BestMove("", played)
+			if err != nil && !errors.Is(err, stockfish.ErrNoMove) {
+				ai.mu.Lock()
+				dropSession(sessionID)
+				ai.mu.Unlock()
+				return chess.NoOutcome, len(played), err
+			}
+			if err == nil {
+				if move, err = (chess.UCINotation{}).Decode(pos, uci); err != nil {
+					slog.Warn("엔진이 둔 수를 읽지 못했습니다", "move", uci, "err", err)
+					move = nil
+				}
+			}
+		}
+		if move == nil {
+			break
+		}
+		played = append(played, move.String())
+		if !playMove(game, move) {
+			break
+		}
+		for _, m := range game.EligibleDraws() {
+			if m == chess.ThreefoldRepetition {
+				game.Draw(m)
+			}
+		}
+	}
+
+	outcome := game.Outcome()
+	if outcome == chess.NoOutcome {
+		outcome = chess.Draw // 최대 반수 초과
+	}
+	result := "Draw"
+	switch outcome {
+	case chess.WhiteWon:
+		result = chess.White.Name()
+	case chess.BlackWon:
+		result = chess.Black.Name()
+	}
+	_, pgns := finishGame(result, game.Position().String(), map[string]chess.Color{sessionID: aiColor}, rating, learningEnabled(nil))
+	for _, pgn := range pgns {
+		if err := appendPGN(pgn); err != nil {
+			slog.Error("PGN 저장 실패", "path", pgnFile, "err", err)
+		}
+	}
+	return outcome, len(played), nil
+}
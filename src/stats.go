@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// /stats와 Prometheus 게이지가 읽는 학습 현황 스냅숏.
+// Q테이블 전체를 훑는 집계는 ai.mu 읽기 잠금을 오래 잡아 /move의 쓰기 잠금을 막으므로,
+// 요청마다 하지 않고 statsMaxAge마다 한 번만 백그라운드에서 새로 만듭니다. 읽는 쪽은 잠금 없이 씁니다.
+type statsSnapshot struct {
+	taken       time.Time
+	GameCount   int
+	States      int
+	Entries     int
+	TotalVisits int
+	MaxStates   int
+	Epsilon     float64
+	Alpha       float64
+	Rating      float64
+	Results     string // 최근 결과 (recentResults로 집계)
+	MinQ, MaxQ  float64
+	SumQ        float64
+}
+
+// 스냅숏을 새로 만드는 간격. /stats 값은 이만큼 늦을 수 있습니다.
+const statsMaxAge = time.Second
+
+var (
+	statsCache   atomic.Pointer[statsSnapshot]
+	statsRefresh sync.Mutex // 한 번에 한 집계만 돕니다
+)
+
+// 현재 스냅숏을 돌려줍니다. 오래됐으면 그대로 돌려주고 백그라운드에서 새로 만듭니다.
+// 아직 스냅숏이 없을 때(시작 직후)만 집계를 기다립니다.
+func currentStats() *statsSnapshot {
+	s := statsCache.Load()
+	if s == nil {
+		return refreshStats(true)
+	}
+	if time.Since(s.taken) > statsMaxAge {
+		go refreshStats(false)
+	}
+	return s
+}
+
+// Q테이블을 훑어 스냅숏을 새로 만듭니다. wait가 false면 다른 집계가 돌고 있을 때 바로 돌아갑니다.
+func refreshStats(wait bool) *statsSnapshot {
+	if wait {
+		statsRefresh.Lock()
+	} else if !statsRefresh.TryLock() {
+		return nil
+	}
+	defer statsRefresh.Unlock()
+	if s := statsCache.Load(); s != nil && time.Since(s.taken) <= statsMaxAge {
+		return s
+	}
+
+	s := &statsSnapshot{taken: time.Now(), MinQ: math.Inf(1), MaxQ: math.Inf(-1)}
+	ai.mu.RLock()
+	for _, actions := range ai.QTable {
+		for _, q := range actions {
+			s.Entries++
+			s.SumQ += q
+			s.MinQ = math.Min(s.MinQ, q)
+			s.MaxQ = math.Max(s.MaxQ, q)
+		}
+	}
+	for _, actions := range ai.Visits {
+		for _, n := range actions {
+			s.TotalVisits += n
+		}
+	}
+	s.GameCount, s.States, s.MaxStates = ai.GameCount, len(ai.QTable), ai.MaxStates
+	s.Epsilon = ai.effectiveEpsilon(ai.GameCount) // decay_schedule을 적용한 현재 값
+	s.Alpha = ai.effectiveAlpha(ai.GameCount)
+	s.Rating, s.Results = ai.Rating, ai.Results
+	ai.mu.RUnlock()
+
+	statsCache.Store(s)
+	return s
+}
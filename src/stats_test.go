@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getStats(t *testing.T) map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	statsHandler(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	newTestAI(t)
+	ai.QTable["a"] = map[string]float64{"e2e4": 1, "d2d4": -3}
+	ai.QTable["b"] = map[string]float64{"g1f3": 5}
+	ai.GameCount = 7
+	ai.Results = "WWDL"
+
+	body := getStats(t)
+	if body["states"] != 2.0 || body["entries"] != 3.0 || body["game_count"] != 7.0 {
+		t.Errorf("stats = %v", body)
+	}
+	if body["min_q"] != -3.0 || body["max_q"] != 5.0 || body["mean_q"] != 1.0 {
+		t.Errorf("q distribution = %v %v %v", body["min_q"], body["max_q"], body["mean_q"])
+	}
+	if recent := body["recent"].(map[string]interface{}); recent["wins"] != 2.0 || recent["games"] != 4.0 {
+		t.Errorf("recent = %v", recent)
+	}
+}
+
+func TestStatsDoesNotBlockOnWriteLock(t *testing.T) {
+	newTestAI(t)
+	ai.GameCount = 1
+	getStats(t)
+
+	// /move가 쓰기 잠금을 잡고 있어도 /stats는 스냅숏으로 바로 답합니다.
+	// 오래된 스냅숏은 그대로 돌려주고, 잠금이 풀린 뒤 백그라운드에서 새로 만듭니다.
+	statsCache.Load().taken = time.Now().Add(-time.Hour)
+	ai.mu.Lock()
+	ai.GameCount = 2
+	done := make(chan map[string]interface{})
+	go func() { done <- getStats(t) }()
+	select {
+	case body := <-done:
+		if body["game_count"] != 1.0 {
+			t.Errorf("game_count = %v, want stale 1", body["game_count"])
+		}
+	case <-time.After(time.Second):
+		t.Error("/stats blocked on ai.mu")
+		ai.mu.Unlock()
+		<-done
+		return
+	}
+	ai.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for currentStats().GameCount != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("snapshot was not refreshed after the lock was released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,194 @@
+// Package stockfish는 UCI 엔진(Stockfish 등)을 하위 프로세스로 띄워
+// 표준 입출력으로 position/go/bestmove를 주고받습니다.
+// 프로세스가 죽거나 응답하지 않으면 한 번 다시 띄워서 재시도합니다.
+package stockfish
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 엔진이 응답하지 않을 때 기다리는 시간
+const (
+	readyTimeout = 10 * time.Second // uciok, readyok
+	moveTimeout  = 60 * time.Second // bestmove
+)
+
+var errExited = errors.New("stockfish: engine exited")
+
+// ErrNoMove는 엔진이 둘 수 있는 수가 없다고 답한 경우입니다. (체크메이트·스테일메이트)
+var ErrNoMove = errors.New("stockfish: no legal move")
+
+// Engine은 UCI 엔진 프로세스 하나입니다. 여러 고루틴에서 써도 됩니다.
+type Engine struct {
+	Path  string // 실행 파일 경로
+	Skill int    // "Skill Level" 옵션 (0~20, 음수면 설정하지 않음)
+	Depth int    // 탐색 깊이 ("go depth N")
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	lines chan string // 엔진 출력 줄. 프로세스가 끝나면 닫힙니다.
+}
+
+// New는 엔진 설정만 만듭니다. 프로세스는 처음 BestMove를 부를 때 띄웁니다.
+func New(path string, skill, depth int) *Engine {
+	return &Engine{Path: path, Skill: skill, Depth: depth}
+}
+
+// Start는 엔진을 띄우고 uci/isready 인사를 마칩니다. 이미 떠 있으면 아무 일도 하지 않습니다.
+func (e *Engine) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.start()
+}
+
+func (e *Engine) start() error {
+	if e.cmd != nil {
+		return nil
+	}
+	cmd := exec.Command(e.Path)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("stockfish: start %s: %w", e.Path, err)
+	}
+	lines := make(chan string, 64)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+		cmd.Wait()
+	}()
+	e.cmd, e.in, e.lines = cmd, in, lines
+
+	if err := e.send("uci"); err != nil {
+		return e.fail(err)
+	}
+	if _, err := e.waitFor("uciok", readyTimeout); err != nil {
+		return e.fail(err)
+	}
+	if e.Skill >= 0 {
+		if err := e.send(fmt.Sprintf("setoption name Skill Level value %d", e.Skill)); err != nil {
+			return e.fail(err)
+		}
+	}
+	if err := e.send("isready"); err != nil {
+		return e.fail(err)
+	}
+	if _, err := e.waitFor("readyok", readyTimeout); err != nil {
+		return e.fail(err)
+	}
+	return nil
+}
+
+// BestMove는 주어진 포지션에서 엔진이 고른 수를 UCI 표기로 돌려줍니다.
+// fen이 비어 있으면 시작 포지션입니다. moves는 그 뒤에 둔 수들입니다.
+// 엔진이 죽었거나 응답하지 않으면 다시 띄워 한 번 더 시도합니다.
+func (e *Engine) BestMove(fen string, moves []string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	move, err := e.bestMove(fen, moves)
+	if err == nil || errors.Is(err, ErrNoMove) {
+		return move, err
+	}
+	return e.bestMove(fen, moves)
+}
+
+func (e *Engine) bestMove(fen string, moves []string) (string, error) {
+	if err := e.start(); err != nil {
+		return "", err
+	}
+	position := "position startpos"
+	if fen != "" {
+		position = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		position += " moves " + strings.Join(moves, " ")
+	}
+	if err := e.send(position); err != nil {
+		return "", e.fail(err)
+	}
+	if err := e.send(fmt.Sprintf("go depth %d", e.Depth)); err != nil {
+		return "", e.fail(err)
+	}
+	line, err := e.waitFor("bestmove", moveTimeout)
+	if err != nil {
+		return "", e.fail(err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[1] == "(none)" || fields[1] == "0000" {
+		return "", ErrNoMove
+	}
+	return fields[1], nil
+}
+
+// Close는 엔진에 quit을 보내고 프로세스를 정리합니다.
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cmd == nil {
+		return nil
+	}
+	e.send("quit")
+	e.stop()
+	return nil
+}
+
+func (e *Engine) send(cmd string) error {
+	_, err := io.WriteString(e.in, cmd+"\n")
+	return err
+}
+
+// prefix로 시작하는 줄이 나올 때까지 엔진 출력을 읽습니다. (info 줄 등은 버림)
+func (e *Engine) waitFor(prefix string, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return "", errExited
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, nil
+			}
+		case <-timer.C:
+			return "", fmt.Errorf("stockfish: no %q within %v", prefix, timeout)
+		}
+	}
+}
+
+// 오류가 난 프로세스를 정리하고 오류를 그대로 돌려줍니다. 다음 호출에서 다시 띄웁니다.
+func (e *Engine) fail(err error) error {
+	e.stop()
+	return err
+}
+
+func (e *Engine) stop() {
+	if e.cmd == nil {
+		return
+	}
+	e.in.Close()
+	if e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	for range e.lines {
+		// 읽기 고루틴이 끝날 때까지 남은 출력을 버립니다.
+	}
+	e.cmd, e.in, e.lines = nil, nil, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// 이보다 작아진 적격 흔적은 버립니다.
+const traceMin = 1e-4
+
+// 적격 흔적의 키 (상태, 수)
+type stateAction struct {
+	state, move string
+}
+
+// [학습] Q(λ): 한 판의 한 단계 전이를 앞에서부터 차례로 갱신합니다. (Peng식, 흔적을 끊지 않음)
+// 수를 둘 때마다 그 상태-수의 흔적을 1로 두고(교체 흔적), 그 수의 TD 오차 δ만큼 흔적이 남은
+// 모든 상태-수를 α·δ·e로 함께 갱신한 뒤 흔적을 γλ배로 줄입니다. 흔적은 판마다 새로 시작합니다.
+// 종료 보상의 오차가 마지막 수 하나가 아니라 최근에 둔 수들에 λ만큼 줄어들며 바로 전해집니다.
+// 선택은 Q값에 탐색 점수를 더해 하므로 Watkins식처럼 Q값 기준 탐욕적이지 않은 수에서 흔적을 끊으면
+// 거의 매 수 끊기게 되어 흔적을 끊지 않습니다.
+func learnTraces(tables qTables, visits map[string]map[string]int, steps []transition, alpha float64, r *rand.Rand) {
+	traces := make(map[stateAction]float64)
+	for _, t := range steps {
+		table, eval := tables.pick(r)
+		ensureRow(tables, table, t.state)
+		target := t.reward
+		if t.next != "" {
+			target += ai.Gamma * eval[t.next][nextAction(table, t)]
+		}
+		delta := target - table[t.state][t.move]
+
+		visited := []stateAction{{t.state, t.move}}
+		// 좌우로 뒤집은 포지션도 같은 흔적을 남깁니다.
+		if m, ok := mirrorTransition(t); ok && ai.Augment {
+			visited = append(visited, stateAction{m.state, m.move})
+		}
+		for _, k := range visited {
+			traces[k] = 1
+		}
+		for k, e := range traces {
+			ensureRow(tables, table, k.state)
+			table[k.state][k.move] += learningRate(visits[k.state][k.move], alpha) * delta * e
+		}
+		for _, k := range visited {
+			if visits[k.state] == nil {
+				visits[k.state] = make(map[string]int)
+			}
+			visits[k.state][k.move]++
+		}
+		for k, e := range traces {
+			if e *= ai.Gamma * ai.Lambda; e < traceMin {
+				delete(traces, k)
+			} else {
+				traces[k] = e
+			}
+		}
+	}
+}
+
+// 갱신할 표와 상태 목록의 기준인 a(QTable)에 state 행을 만들어 둡니다.
+// 행을 갱신하기 직전에 부르므로 여기서 바뀐 상태로 표시합니다.
+func ensureRow(tables qTables, table map[string]map[string]float64, state string) {
+	if tables.dirty != nil {
+		tables.dirty[state] = struct{}{}
+	}
+	if tables.a[state] == nil {
+		tables.a[state] = make(map[string]float64)
+	}
+	if table[state] == nil {
+		table[state] = make(map[string]float64)
+	}
+}
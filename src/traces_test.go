@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTracesDecayEarlierUpdates(t *testing.T) {
+	newTestAI(t)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment, ai.NStep = false, false, false, 1
+	ai.Gamma, ai.Decay = 1, 0 // 종료 보상은 마지막 수만 받습니다
+	history := []string{"s0|m0|0|s1", "s1|m1|0|s2", "s2|m2|0|"}
+	run := func(lambda float64) map[string]map[string]float64 {
+		ai.Lambda = lambda
+		tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+		learnInto(tables, make(map[string]map[string]int), history, 10, 1, rand.New(rand.NewSource(1)))
+		return tables.a
+	}
+
+	// 마지막 수의 TD 오차 10이 흔적을 따라 한 수 앞마다 γλ = 0.5배로 전해집니다.
+	q := run(0.5)
+	for _, c := range []struct {
+		state, move string
+		want        float64
+	}{{"s2", "m2", 10}, {"s1", "m1", 5}, {"s0", "m0", 2.5}} {
+		if got := q[c.state][c.move]; got != c.want {
+			t.Errorf("lambda 0.5: Q(%s,%s) = %v, want %v", c.state, c.move, got, c.want)
+		}
+	}
+
+	// λ = 1이면 줄지 않고 모두 같은 값을 받습니다.
+	q = run(1)
+	if q["s0"]["m0"] != 10 || q["s1"]["m1"] != 10 {
+		t.Errorf("lambda 1: Q = %v, want 10 everywhere", q)
+	}
+}
+
+func TestTracesResetEachGame(t *testing.T) {
+	newTestAI(t)
+	ai.DoubleQ, ai.AdaptiveAlpha, ai.Augment = false, false, false
+	ai.Gamma, ai.Lambda = 1, 1
+	tables := qTables{a: make(map[string]map[string]float64), b: make(map[string]map[string]float64)}
+	visits := make(map[string]map[string]int)
+	r := rand.New(rand.NewSource(1))
+	learnTraces(tables, visits, []transition{{state: "a", move: "x", reward: 0}}, 1, r)
+	// 다음 판의 오차는 지난 판의 상태-수에 전해지지 않습니다.
+	learnTraces(tables, visits, []transition{{state: "b", move: "y", reward: 4}}, 1, r)
+	if tables.a["a"]["x"] != 0 || tables.a["b"]["y"] != 4 {
+		t.Errorf("Q = %v, want a/x 0 and b/y 4", tables.a)
+	}
+}
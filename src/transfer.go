@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// 가져오기 요청 본문의 최대 크기
+const maxImportUpload = 512 << 20
+
+// 내보내기·가져오기에 쓰는 Q테이블 파일 형식 (qtable.json과 같음)
+type brainFile struct {
+	QTable    map[string]map[string]float64 `json:"q_table"`
+	QB        map[string]map[string]float64 `json:"q_table_b,omitempty"`
+	Visits    map[string]map[string]int     `json:"visits"`
+	GameCount int                           `json:"game_count"`
+}
+
+// GET /export[?gzip=1]: 저장 파일과 같은 형식의 Q테이블을 내려받습니다.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "GET only")
+		return
+	}
+	compress := r.URL.Query().Get("gzip") == "1" || r.URL.Query().Get("gzip") == "true"
+
+	var out io.Writer = w
+	if compress {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="qtable.json.gz"`)
+		zw := gzip.NewWriter(w)
+		defer zw.Close()
+		out = zw
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="qtable.json"`)
+	}
+
+	ai.mu.RLock()
+	defer ai.mu.RUnlock()
+	json.NewEncoder(out).Encode(brainFile{
+		QTable:    ai.QTable,
+		QB:        ai.QB,
+		Visits:    ai.Visits,
+		GameCount: ai.GameCount,
+	})
+}
+
+// POST /import[?merge=1&combine=sum|average|max]
+// 본문의 Q테이블(JSON 또는 gzip)을 읽습니다. merge가 없으면 지금 테이블을 통째로 바꾸고,
+// merge=1이면 기존 테이블에 합칩니다. 양쪽에 있는 상태-수의 Q값은 combine에 따라
+// 더하거나(sum) 방문 횟수로 가중 평균을 내거나(average, 기본값) 큰 값을 쓰고(max),
+// 방문 횟수와 대국 수는 더합니다.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	q := r.URL.Query()
+	merge := q.Get("merge") == "1" || q.Get("merge") == "true"
+	combine := q.Get("combine")
+	if combine == "" {
+		combine = mergeAverage
+	}
+	if !validMergeMode(combine) {
+		writeError(w, http.StatusBadRequest, "combine must be sum, average or max")
+		return
+	}
+
+	// gzip 매직 넘버(1f 8b)로 압축 여부를 판단합니다.
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxImportUpload))
+	var in io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid gzip: "+err.Error())
+			return
+		}
+		defer zr.Close()
+		in = zr
+	}
+	var brain brainFile
+	if err := json.NewDecoder(in).Decode(&brain); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid Q-table: "+err.Error())
+		return
+	}
+	// 예전 형식 키로 내보낸 파일도 받을 수 있게 키를 정규화합니다.
+	brain.normalize()
+
+	ai.mu.Lock()
+	if !merge {
+		// 바꾸기는 빈 테이블에 합치는 것과 같습니다.
+		ai.QTable = make(map[string]map[string]float64)
+		ai.QB = make(map[string]map[string]float64)
+		ai.Visits = make(map[string]map[string]int)
+		ai.GameCount = 0
+	}
+	current := brainFile{QTable: ai.QTable, QB: ai.QB, Visits: ai.Visits, GameCount: ai.GameCount}
+	current.merge(brain, combine)
+	ai.GameCount = current.GameCount
+	evictStates()
+	needSnapshot()
+	brainSize, gameCount := len(ai.QTable), ai.GameCount
+	ai.mu.Unlock()
+	saveToFile()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"imported":   len(brain.QTable),
+		"merged":     merge,
+		"brain_size": brainSize,
+		"game_count": gameCount,
+	})
+}
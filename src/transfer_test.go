@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func exportBrain(t *testing.T, query string) []byte {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	exportHandler(rec, httptest.NewRequest(http.MethodGet, "/export"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d", rec.Code)
+	}
+	return rec.Body.Bytes()
+}
+
+func importBrain(t *testing.T, query string, data []byte) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	importHandler(rec, httptest.NewRequest(http.MethodPost, "/import"+query, bytes.NewReader(data)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, query := range []string{"", "?gzip=1"} {
+		newTestAI(t)
+		ai.QTable["s"] = map[string]float64{"e2e4": 1.5, "d2d4": -2}
+		ai.Visits["s"] = map[string]int{"e2e4": 3}
+		ai.GameCount = 12
+		data := exportBrain(t, query)
+
+		// 새 테이블에 바꿔 넣으면 그대로 돌아옵니다.
+		newTestAI(t)
+		ai.QTable["other"] = map[string]float64{"a2a3": 9}
+		importBrain(t, "", data)
+		if len(ai.QTable) != 1 || ai.QTable["s"]["e2e4"] != 1.5 || ai.QTable["s"]["d2d4"] != -2 {
+			t.Errorf("%q: QTable after import = %v", query, ai.QTable)
+		}
+		if ai.Visits["s"]["e2e4"] != 3 || ai.GameCount != 12 {
+			t.Errorf("%q: visits %v, game count %d", query, ai.Visits, ai.GameCount)
+		}
+
+		// 같은 것을 sum으로 합치면 값과 대국 수가 두 배가 됩니다.
+		importBrain(t, "?merge=1&combine=sum", data)
+		if ai.QTable["s"]["e2e4"] != 3 || ai.Visits["s"]["e2e4"] != 6 || ai.GameCount != 24 {
+			t.Errorf("%q: after sum merge: %v %v %d", query, ai.QTable["s"], ai.Visits["s"], ai.GameCount)
+		}
+	}
+}
+
+func TestImportRejectsBadInput(t *testing.T) {
+	newTestAI(t)
+	for _, tt := range []struct{ query, body string }{
+		{"", "not json"},
+		{"?merge=1&combine=median", `{"q_table": {}}`},
+	} {
+		rec := httptest.NewRecorder()
+		importHandler(rec, httptest.NewRequest(http.MethodPost, "/import"+tt.query, bytes.NewReader([]byte(tt.body))))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q %q: status %d, want 400", tt.query, tt.body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// UCI 모드: 표준 입력으로 UCI 명령을 받아 표준 출력으로 응답합니다.
+// Arena, CuteChess 같은 GUI나 lichess-bot에 엔진으로 붙일 수 있습니다.
+func runUCI(in io.Reader, out io.Writer) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\n", args...)
+		w.Flush()
+	}
+
+	game := chess.NewGame()
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			reply("id name RL Chess Bot")
+			reply("uciok")
+		case "isready":
+			reply("readyok")
+		case "ucinewgame":
+			game = chess.NewGame()
+		case "position":
+			g, err := uciPosition(fields[1:])
+			if err != nil {
+				reply("info string %v", err)
+				continue
+			}
+			game = g
+		case "go":
+			move := bestMove(game.Position())
+			if move == nil {
+				reply("bestmove 0000")
+				continue
+			}
+			reply("bestmove %s", move.String())
+		case "quit":
+			return
+		}
+	}
+}
+
+// "position [startpos | fen <FEN>] [moves <m1> <m2> ...]"를 게임으로 만듭니다.
+func uciPosition(args []string) (*chess.Game, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("position: missing arguments")
+	}
+
+	var game *chess.Game
+	rest := args[1:]
+	switch args[0] {
+	case "startpos":
+		game = chess.NewGame()
+	case "fen":
+		end := len(rest)
+		for i, a := range rest {
+			if a == "moves" {
+				end = i
+				break
+			}
+		}
+		g, err := parseFEN(strings.Join(rest[:end], " "))
+		if err != nil {
+			return nil, err
+		}
+		game = g
+		rest = rest[end:]
+	default:
+		return nil, fmt.Errorf("position: unknown argument %q", args[0])
+	}
+
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, s := range rest[1:] {
+			m, err := chess.UCINotation{}.Decode(game.Position(), s)
+			if err != nil {
+				return nil, err
+			}
+			if err := game.Move(m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return game, nil
+}
+
+// /move와 같은 오프닝 북, Q값+탐색 점수로 가장 좋은 수를 고릅니다.
+// 대국용이므로 무작위 탐색(epsilon)은 하지 않고 기록도 남기지 않습니다.
+func bestMove(pos *chess.Position) *chess.Move {
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+	state := stateKey(pos)
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	if ai.UseBook {
+		if m := bookMove(pos, ai.rng); m != nil {
+			return m
+		}
+	}
+	ranked := rankMoves(pos, moves, qValues(state), tt, nil)
+	return topMove(ranked, ai.rng)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RFC 6455 핸드셰이크에서 Sec-WebSocket-Key 뒤에 붙이는 고정 문자열
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsMaxMessage   = 64 << 10         // 받는 메시지 최대 크기 (조각을 합친 크기)
+	wsIdleTimeout  = sessionTTL       // 이 시간 동안 메시지가 없으면 연결을 끊음
+	wsWriteTimeout = 10 * time.Second // 응답 한 번을 쓰는 데 기다리는 시간
+)
+
+// 프레임 종류 (opcode)
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// 닫기 상태 코드
+const (
+	wsCloseNormal   = 1000
+	wsCloseProtocol = 1002
+	wsCloseTooBig   = 1009
+)
+
+var errWSClosed = errors.New("websocket: closed by peer")
+
+// 연결마다 세션 ID를 붙일 때 쓰는 번호
+var wsConnSeq atomic.Uint64
+
+// 열려 있는 웹소켓 연결. 하이재킹한 연결은 http.Server.Shutdown이 기다리거나 닫지 않으므로
+// 서버를 끌 때 closeWebSockets로 직접 닫습니다. closed 뒤에 들어온 연결은 받지 않습니다.
+var wsConns = struct {
+	sync.Mutex
+	conns  map[*wsConn]struct{}
+	closed bool
+}{conns: make(map[*wsConn]struct{})}
+
+// 연결을 목록에 넣습니다. 서버가 이미 종료 중이면 false입니다.
+func trackWS(ws *wsConn) bool {
+	wsConns.Lock()
+	defer wsConns.Unlock()
+	if wsConns.closed {
+		return false
+	}
+	wsConns.conns[ws] = struct{}{}
+	return true
+}
+
+func untrackWS(ws *wsConn) {
+	wsConns.Lock()
+	delete(wsConns.conns, ws)
+	wsConns.Unlock()
+}
+
+// 열린 웹소켓 연결을 모두 닫습니다. 읽던 핸들러는 오류로 빠져나가며 세션을 지웁니다.
+// 서버 종료 때 srv.RegisterOnShutdown으로 부릅니다.
+func closeWebSockets() {
+	wsConns.Lock()
+	defer wsConns.Unlock()
+	wsConns.closed = true
+	for ws := range wsConns.conns {
+		ws.conn.Close()
+	}
+	clear(wsConns.conns)
+}
+
+// 웹소켓 연결 하나. 서버 쪽이므로 보내는 프레임은 마스킹하지 않습니다.
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// GET /ws
+// 연결 하나가 세션 하나입니다. 클라이언트는 /move 요청과 같은 JSON을 텍스트 메시지로 보내고
+// (session_id는 무시), /move 응답과 같은 JSON을 받습니다. 연결이 끊기면 세션 기록을 지웁니다.
+// limit이 있으면 메시지마다 /move와 같은 요청 제한을 적용합니다.
+func wsHandler(limit *rateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "GET only")
+			return
+		}
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+			writeError(w, http.StatusBadRequest, "websocket upgrade required")
+			return
+		}
+		if r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			writeError(w, http.StatusUpgradeRequired, "unsupported websocket version")
+			return
+		}
+		ws, err := upgrade(w, key)
+		if err != nil {
+			slog.Error("웹소켓 연결 실패", "err", err)
+			return
+		}
+		defer ws.conn.Close()
+		if !trackWS(ws) {
+			return
+		}
+		defer untrackWS(ws)
+
+		sessionID := fmt.Sprintf("ws-%d", wsConnSeq.Add(1))
+		ip := clientIP(r, ai.TrustProxy)
+		slog.Info("웹소켓 연결", "session", sessionID, "remote", ip)
+		defer func() {
+			ai.mu.Lock()
+			dropSession(sessionID)
+			ai.mu.Unlock()
+			slog.Info("웹소켓 연결 종료", "session", sessionID)
+		}()
+
+		for {
+			ws.conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+			msg, err := ws.readMessage()
+			if err != nil {
+				if !errors.Is(err, errWSClosed) && !errors.Is(err, io.EOF) {
+					slog.Warn("웹소켓 읽기 실패", "session", sessionID, "err", err)
+				}
+				return
+			}
+			status, resp := wsReply(msg, sessionID, ip, limit)
+			if status != http.StatusOK {
+				// HTTP 상태 코드가 없으므로 오류 응답의 code에 같은 값을 담아 보냅니다.
+				if body, ok := resp.(map[string]string); ok {
+					body["code"] = strconv.Itoa(status)
+				}
+			}
+			out, err := json.Marshal(resp)
+			if err == nil {
+				err = ws.writeFrame(wsText, out)
+			}
+			if err != nil {
+				slog.Warn("웹소켓 쓰기 실패", "session", sessionID, "err", err)
+				return
+			}
+		}
+	}
+}
+
+// 메시지 하나를 /move 요청으로 처리합니다.
+func wsReply(msg []byte, sessionID, ip string, limit *rateLimiter) (int, interface{}) {
+	if limit != nil {
+		if ok, _ := limit.allow(ip); !ok {
+			return http.StatusTooManyRequests, errorBody(http.StatusTooManyRequests, "rate limit exceeded")
+		}
+	}
+	var req moveRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return http.StatusBadRequest, errorBody(http.StatusBadRequest, "invalid JSON")
+	}
+	req.SessionID = sessionID
+	return handleMove(req)
+}
+
+// Connection·Upgrade처럼 쉼표로 나열하는 헤더에 token이 있는지 봅니다. (대소문자 무시)
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 연결을 가로채 101 Switching Protocols로 답합니다.
+func upgrade(w http.ResponseWriter, key string) (*wsConn, error) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// 데이터 메시지 하나를 읽습니다. 조각난 메시지는 합치고, 사이에 끼는 ping에는 pong으로 답합니다.
+// 상대가 닫으면 닫기 프레임을 돌려보내고 errWSClosed를 돌려줍니다.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.writeClose(wsCloseNormal)
+			return nil, errWSClosed
+		case wsText, wsBinary:
+			if started {
+				c.writeClose(wsCloseProtocol)
+				return nil, errors.New("websocket: new message before previous one finished")
+			}
+			started = true
+		case wsContinuation:
+			if !started {
+				c.writeClose(wsCloseProtocol)
+				return nil, errors.New("websocket: continuation without a message")
+			}
+		default:
+			c.writeClose(wsCloseProtocol)
+			return nil, fmt.Errorf("websocket: unknown opcode %#x", op)
+		}
+		if len(msg)+len(payload) > wsMaxMessage {
+			c.writeClose(wsCloseTooBig)
+			return nil, errors.New("websocket: message too large")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// 프레임 하나를 읽어 마스킹을 풉니다. 클라이언트가 보낸 프레임은 반드시 마스킹되어 있어야 합니다.
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.rw, head[:]); err != nil {
+		return
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if !masked {
+		c.writeClose(wsCloseProtocol)
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+	if n > wsMaxMessage {
+		c.writeClose(wsCloseTooBig)
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// 마스킹하지 않은 단일 프레임을 씁니다.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	head := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		head = append(head, byte(n))
+	case n <= 0xFFFF:
+		head = append(head, 126)
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head = append(head, 127)
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	if _, err := c.rw.Write(head); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// 닫기 프레임을 보냅니다. 이미 끊긴 연결이면 오류는 무시합니다.
+func (c *wsConn) writeClose(code uint16) {
+	c.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, code))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 웹소켓 핸드셰이크까지 마친 클라이언트 연결을 돌려줍니다.
+func dialWS(t *testing.T, srv *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d", resp.StatusCode)
+	}
+	return conn, br
+}
+
+func TestCloseWebSocketsOnShutdown(t *testing.T) {
+	newTestAI(t)
+	t.Cleanup(func() {
+		wsConns.Lock()
+		wsConns.closed = false
+		wsConns.Unlock()
+	})
+	srv := httptest.NewServer(wsHandler(nil))
+	defer srv.Close()
+
+	conn, br := dialWS(t, srv)
+	// 핸들러가 연결을 목록에 넣을 때까지 기다립니다.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		wsConns.Lock()
+		n := len(wsConns.conns)
+		wsConns.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connection was not tracked")
+		}
+	}
+
+	closeWebSockets()
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("connection still open after closeWebSockets")
+	}
+	conn.Close()
+
+	// 종료가 시작된 뒤에 들어온 연결은 바로 닫습니다.
+	_, br = dialWS(t, srv)
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("connection accepted after shutdown")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/notnil/chess"
+)
+
+// 조브리스트 해시용 난수 키. 서버를 재시작해도 같은 해시가 나오도록 시드를 고정합니다.
+var (
+	zobristPieces    [12][64]uint64 // [기물-1][칸]
+	zobristBlackTurn uint64
+	zobristCastle    [4]uint64 // K, Q, k, q
+	zobristEnPassant [8]uint64 // 앙파상 가능한 파일
+)
+
+func init() {
+	r := rand.New(rand.NewSource(20240601))
+	for p := range zobristPieces {
+		for sq := range zobristPieces[p] {
+			zobristPieces[p][sq] = r.Uint64()
+		}
+	}
+	zobristBlackTurn = r.Uint64()
+	for i := range zobristCastle {
+		zobristCastle[i] = r.Uint64()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = r.Uint64()
+	}
+}
+
+// Zobrist는 기물 배치, 차례, 캐슬링 권리, 앙파상 칸을 차례로 XOR해 포지션 해시를 만듭니다.
+func Zobrist(pos *chess.Position) uint64 {
+	var h uint64
+	board := pos.Board()
+	for i := 0; i < 64; i++ {
+		p := board.Piece(chess.Square(i))
+		if p != chess.NoPiece {
+			h ^= zobristPieces[p-1][i]
+		}
+	}
+	if pos.Turn() == chess.Black {
+		h ^= zobristBlackTurn
+	}
+	cr := pos.CastleRights()
+	for i, c := range []struct {
+		color chess.Color
+		side  chess.Side
+	}{
+		{chess.White, chess.KingSide},
+		{chess.White, chess.QueenSide},
+		{chess.Black, chess.KingSide},
+		{chess.Black, chess.QueenSide},
+	} {
+		if cr.CanCastle(c.color, c.side) {
+			h ^= zobristCastle[i]
+		}
+	}
+	if ep := pos.EnPassantSquare(); ep != chess.NoSquare {
+		h ^= zobristEnPassant[ep.File()]
+	}
+	return h
+}